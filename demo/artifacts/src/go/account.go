@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// docTypeAccount marks account documents in the state database
+const docTypeAccount = "account"
+
+// defaultCurrency is used when an account is opened without a currency and for migrated legacy balances
+const defaultCurrency = "CNY"
+
+// Owner identifies the client that opened an account
+type Owner struct {
+	MSPID string `json:"mspId"`
+	ID    string `json:"id"`
+}
+
+// Account is the JSON document stored under the account name
+type Account struct {
+	DocType   string    `json:"docType"`
+	ID        string    `json:"id"`
+	Owner     Owner     `json:"owner"`
+	Balance   int       `json:"balance"`
+	Currency  string    `json:"currency"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// txTime returns the timestamp the client put in the transaction, it is the same on every endorser
+func txTime(stub shim.ChaincodeStubInterface) (time.Time, error) {
+	ts, err := stub.GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ptypes.Timestamp(ts)
+}
+
+// creator returns the MSP ID and certificate ID of the client submitting the transaction
+func creator(stub shim.ChaincodeStubInterface) (Owner, error) {
+	mspID, err := cid.GetMSPID(stub)
+	if err != nil {
+		return Owner{}, err
+	}
+	id, err := cid.GetID(stub)
+	if err != nil {
+		return Owner{}, err
+	}
+	return Owner{MSPID: mspID, ID: id}, nil
+}
+
+// requireAdmin fails unless the client's certificate carries admin=true, as issued to the admins of the web app
+func requireAdmin(stub shim.ChaincodeStubInterface) error {
+	return cid.AssertAttributeValue(stub, "admin", "true")
+}
+
+// decodeAccount parses a stored value, legacy plain integer balances are converted without an owner
+func decodeAccount(name string, value []byte) (*Account, bool, error) {
+	if len(value) > 0 && value[0] == '{' {
+		account := &Account{}
+		if err := json.Unmarshal(value, account); err != nil {
+			return nil, false, err
+		}
+		return account, false, nil
+	}
+
+	balance, err := strconv.Atoi(string(value))
+	if err != nil {
+		return nil, false, err
+	}
+	return &Account{DocType: docTypeAccount, ID: name, Balance: balance, Currency: defaultCurrency}, true, nil
+}
+
+// getAccount reads an account, the response is set when the account is missing or its state can't be decoded
+func getAccount(stub shim.ChaincodeStubInterface, name string) (*Account, *pb.Response) {
+	value, err := stub.GetState(name)
+	if err != nil {
+		resp := shim.Error("Failed to get state")
+		return nil, &resp
+	}
+	if value == nil {
+		resp := errorResponse(statusNotFound, codeNotFound, "Entity "+name+" not found")
+		return nil, &resp
+	}
+
+	account, _, err := decodeAccount(name, value)
+	if err != nil {
+		resp := errorResponse(statusConflict, codeCorruptedState, "State of "+name+" is neither an account nor an integer")
+		return nil, &resp
+	}
+	return account, nil
+}
+
+// putAccount writes an account, UpdatedAt is set to the transaction time and CreatedAt too for a new account
+func putAccount(stub shim.ChaincodeStubInterface, account *Account) error {
+	now, err := txTime(stub)
+	if err != nil {
+		return err
+	}
+	if account.CreatedAt.IsZero() {
+		account.CreatedAt = now
+	}
+	account.UpdatedAt = now
+
+	value, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	return stub.PutState(account.ID, value)
+}
+
+// AccountPage is a page of accounts, Bookmark is passed back to fetch the next page and is empty on the last one
+type AccountPage struct {
+	Records  []*Account `json:"records"`
+	Bookmark string     `json:"bookmark"`
+	Count    int32      `json:"count"`
+}
+
+// parsePage reads the page size and optional bookmark arguments of a paginated query
+func parsePage(args []string) (int32, string, *pb.Response) {
+	if len(args) != 1 && len(args) != 2 {
+		resp := errorResponse(statusBadRequest, codeInvalidArgument, "Incorrect number of arguments. Expecting page size and optional bookmark")
+		return 0, "", &resp
+	}
+
+	pageSize, err := strconv.ParseInt(args[0], 10, 32)
+	if err != nil || pageSize <= 0 {
+		resp := errorResponse(statusBadRequest, codeInvalidArgument, "Invalid page size, expecting a positive integer value")
+		return 0, "", &resp
+	}
+
+	bookmark := ""
+	if len(args) == 2 {
+		bookmark = args[1]
+	}
+	return int32(pageSize), bookmark, nil
+}
+
+// readAccountPage collects the accounts of a query iterator, values that are not accounts are skipped
+func readAccountPage(iter shim.StateQueryIteratorInterface, metadata *pb.QueryResponseMetadata) (*AccountPage, error) {
+	defer iter.Close()
+
+	page := &AccountPage{Records: []*Account{}}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		account, _, err := decodeAccount(kv.Key, kv.Value)
+		if err != nil {
+			logger.Warningf("Skip %s, its state is neither an account nor an integer", kv.Key)
+			continue
+		}
+		page.Records = append(page.Records, account)
+	}
+
+	if metadata != nil {
+		page.Bookmark = metadata.Bookmark
+		page.Count = metadata.FetchedRecordsCount
+	}
+	return page, nil
+}
+
+// accountQuery restricts a Mango query or a bare selector to account documents.
+// Paging is done with the page size and bookmark, limit and skip are not allowed
+func accountQuery(raw string) (string, error) {
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &query); err != nil {
+		return "", err
+	}
+	if _, ok := query["selector"]; !ok {
+		query = map[string]interface{}{"selector": query}
+	}
+	if _, ok := query["limit"]; ok {
+		return "", errors.New("limit is not allowed, use the page size")
+	}
+	if _, ok := query["skip"]; ok {
+		return "", errors.New("skip is not allowed, use the bookmark")
+	}
+
+	selector, ok := query["selector"].(map[string]interface{})
+	if !ok {
+		return "", errors.New("selector must be an object")
+	}
+	selector["docType"] = docTypeAccount
+
+	data, err := json.Marshal(query)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
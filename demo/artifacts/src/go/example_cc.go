@@ -18,25 +18,71 @@ package main
 
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
+	"time"
 
+	"github.com/golang/protobuf/ptypes"
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	pb "github.com/hyperledger/fabric/protos/peer"
 )
 
 var logger = shim.NewLogger("example_cc0")
 
+// Error codes prefix the message of a failed response, the status tells the gateway how to answer the client
+const (
+	codeInvalidArgument   = "INVALID_ARGUMENT"
+	codeNotFound          = "NOT_FOUND"
+	codeInsufficientFunds = "INSUFFICIENT_FUNDS"
+	codeCorruptedState    = "CORRUPTED_STATE"
+	codeAlreadyExists     = "ALREADY_EXISTS"
+	codePermissionDenied  = "PERMISSION_DENIED"
+)
+
+// Response statuses of the error codes, any status from 400 fails the endorsement
+const (
+	statusBadRequest = 400
+	statusForbidden  = 403
+	statusNotFound   = 404
+	statusConflict   = 409
+)
+
+// errorResponse builds a failed response carrying a typed error code
+func errorResponse(status int32, code, msg string) pb.Response {
+	logger.Errorf("%s: %s", code, msg)
+	return pb.Response{Status: status, Message: code + ": " + msg}
+}
+
 // SimpleChaincode example simple Chaincode implementation
 type SimpleChaincode struct {
 }
 
-func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response  {
+// Init creates the initial accounts, the arguments after the function name are name/amount pairs
+func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
 	logger.Info("########### example_cc0 Init ###########")
 
-	return shim.Success(nil)
+	_, args := stub.GetFunctionAndParameters()
+	if len(args)%2 != 0 {
+		return errorResponse(statusBadRequest, codeInvalidArgument, "Incorrect number of arguments. Expecting name/amount pairs")
+	}
 
+	// Reads don't see the writes of the same transaction, so duplicates are caught here
+	seen := make(map[string]bool)
+	for i := 0; i < len(args); i += 2 {
+		name := args[i]
+		if seen[name] {
+			return errorResponse(statusBadRequest, codeInvalidArgument, "Duplicate account "+name)
+		}
+		seen[name] = true
+
+		resp := t.open(stub, args[i:i+2])
+		if resp.Status != shim.OK {
+			return resp
+		}
+	}
 
+	return shim.Success(nil)
 }
 
 // Transaction makes payment of X units from A to B
@@ -58,9 +104,37 @@ func (t *SimpleChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 		// Deletes an entity from its state
 		return t.move(stub, args)
 	}
+	if function == "open" || function == "create" {
+		// Creates an account with its initial balance
+		return t.open(stub, args)
+	}
+	if function == "migrate" {
+		// Converts legacy balances into account documents
+		return t.migrate(stub, args)
+	}
+	if function == "history" {
+		// queries every modification of an entity
+		return t.history(stub, args)
+	}
+	if function == "list" {
+		// queries a page of all the accounts
+		return t.list(stub, args)
+	}
+	if function == "richQuery" {
+		// queries a page of the accounts matching a CouchDB selector
+		return t.richQuery(stub, args)
+	}
+	if function == "putPrivate" {
+		// Stores the transient values in a private data collection
+		return t.putPrivate(stub, args)
+	}
+	if function == "getPrivate" {
+		// queries a value of a private data collection
+		return t.getPrivate(stub, args)
+	}
 
-	logger.Errorf("Unknown action, check the first argument, must be one of 'delete', 'query', or 'move'. But got: %v", args[0])
-	return shim.Error(fmt.Sprintf("Unknown action, check the first argument, must be one of 'delete', 'query', or 'move'. But got: %v", args[0]))
+	logger.Errorf("Unknown action, check the first argument, must be one of 'delete', 'query', 'move', 'open', 'migrate', 'history', 'list', 'richQuery', 'putPrivate' or 'getPrivate'. But got: %v", function)
+	return shim.Error(fmt.Sprintf("Unknown action, check the first argument, must be one of 'delete', 'query', 'move', 'open', 'migrate', 'history', 'list', 'richQuery', 'putPrivate' or 'getPrivate'. But got: %v", function))
 }
 
 func (t *SimpleChaincode) move(stub shim.ChaincodeStubInterface, args []string) pb.Response {
@@ -71,65 +145,184 @@ func (t *SimpleChaincode) move(stub shim.ChaincodeStubInterface, args []string)
 	var err error
 
 	if len(args) != 3 {
-		return shim.Error("Incorrect number of arguments. Expecting 4, function followed by 2 names and 1 value")
+		return errorResponse(statusBadRequest, codeInvalidArgument, "Incorrect number of arguments. Expecting 4, function followed by 2 names and 1 value")
 	}
 
 	A = args[0]
 	B = args[1]
+	if A == B {
+		return errorResponse(statusBadRequest, codeInvalidArgument, "Cannot transfer from "+A+" to itself")
+	}
+
+	// Validate the amount before touching the state
+	X, err = strconv.Atoi(args[2])
+	if err != nil {
+		return errorResponse(statusBadRequest, codeInvalidArgument, "Invalid transaction amount, expecting a integer value")
+	}
+	if X <= 0 {
+		return errorResponse(statusBadRequest, codeInvalidArgument, "Transaction amount must be positive")
+	}
 
 	// Get the state from the ledger
-	// TODO: will be nice to have a GetAllState call to ledger
-	Avalbytes, err := stub.GetState(A)
+	Aaccount, resp := getAccount(stub, A)
+	if resp != nil {
+		return *resp
+	}
+	Baccount, resp := getAccount(stub, B)
+	if resp != nil {
+		return *resp
+	}
+	if Aaccount.Currency != Baccount.Currency {
+		return errorResponse(statusBadRequest, codeInvalidArgument, "Cannot transfer from "+Aaccount.Currency+" to "+Baccount.Currency)
+	}
+
+	// Perform the execution
+	Aval, Bval = Aaccount.Balance, Baccount.Balance
+	if Aval < X {
+		return errorResponse(statusConflict, codeInsufficientFunds, fmt.Sprintf("Balance of %s is %d, cannot transfer %d", A, Aval, X))
+	}
+	Aaccount.Balance = Aval - X
+	Baccount.Balance = Bval + X
+	logger.Infof("Aval = %d, Bval = %d\n", Aaccount.Balance, Baccount.Balance)
+
+	// Write the state back to the ledger, legacy balances are stored as accounts from now on
+	err = putAccount(stub, Aaccount)
 	if err != nil {
-		return shim.Error("Failed to get state")
+		return shim.Error(err.Error())
 	}
-	if Avalbytes == nil {
-		return shim.Error("Entity not found")
+
+	err = putAccount(stub, Baccount)
+	if err != nil {
+		return shim.Error(err.Error())
 	}
-	Aval, _ = strconv.Atoi(string(Avalbytes))
 
-	Bvalbytes, err := stub.GetState(B)
+	return shim.Success(nil)
+}
+
+// Creates an account with its initial amount and optional currency, the creator becomes its owner.
+// Existing accounts are never overwritten
+func (t *SimpleChaincode) open(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 && len(args) != 3 {
+		return errorResponse(statusBadRequest, codeInvalidArgument, "Incorrect number of arguments. Expecting name of the account, initial amount and optional currency")
+	}
+
+	A := args[0]
+	if A == "" {
+		return errorResponse(statusBadRequest, codeInvalidArgument, "Account name must not be empty")
+	}
+
+	Aval, err := strconv.Atoi(args[1])
+	if err != nil {
+		return errorResponse(statusBadRequest, codeInvalidArgument, "Invalid initial amount, expecting a integer value")
+	}
+	if Aval < 0 {
+		return errorResponse(statusBadRequest, codeInvalidArgument, "Initial amount must not be negative")
+	}
+
+	currency := defaultCurrency
+	if len(args) == 3 && args[2] != "" {
+		currency = args[2]
+	}
+
+	Avalbytes, err := stub.GetState(A)
 	if err != nil {
 		return shim.Error("Failed to get state")
 	}
-	if Bvalbytes == nil {
-		return shim.Error("Entity not found")
+	if Avalbytes != nil {
+		return errorResponse(statusConflict, codeAlreadyExists, "Account "+A+" already exists")
 	}
-	Bval, _ = strconv.Atoi(string(Bvalbytes))
 
-	// Perform the execution
-	X, err = strconv.Atoi(args[2])
+	owner, err := creator(stub)
 	if err != nil {
-		return shim.Error("Invalid transaction amount, expecting a integer value")
+		return shim.Error("Failed to get creator identity: " + err.Error())
 	}
-	Aval = Aval - X
-	Bval = Bval + X
-	logger.Infof("Aval = %d, Bval = %d\n", Aval, Bval)
 
-	// Write the state back to the ledger
-	err = stub.PutState(A, []byte(strconv.Itoa(Aval)))
+	account := &Account{DocType: docTypeAccount, ID: A, Owner: owner, Balance: Aval, Currency: currency}
+	err = putAccount(stub, account)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
+	logger.Infof("Account %s opened with %d %s\n", A, Aval, currency)
+
+	return shim.Success(nil)
+}
+
+// Converts legacy plain integer balances into account documents, every key is checked when no name is given.
+// Only admins may run it, the migrated accounts keep the empty owner of the legacy balances.
+// Returns the names of the migrated accounts
+func (t *SimpleChaincode) migrate(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if err := requireAdmin(stub); err != nil {
+		return errorResponse(statusForbidden, codePermissionDenied, "Only admins can migrate accounts")
+	}
 
-	err = stub.PutState(B, []byte(strconv.Itoa(Bval)))
+	names := args
+	if len(names) == 0 {
+		iter, err := stub.GetStateByRange("", "")
+		if err != nil {
+			return shim.Error("Failed to get state by range")
+		}
+		defer iter.Close()
+
+		for iter.HasNext() {
+			kv, err := iter.Next()
+			if err != nil {
+				return shim.Error(err.Error())
+			}
+			names = append(names, kv.Key)
+		}
+	}
+
+	migrated := []string{}
+	for _, name := range names {
+		value, err := stub.GetState(name)
+		if err != nil {
+			return shim.Error("Failed to get state")
+		}
+		if value == nil {
+			return errorResponse(statusNotFound, codeNotFound, "Entity "+name+" not found")
+		}
+
+		account, legacy, err := decodeAccount(name, value)
+		if err != nil {
+			return errorResponse(statusConflict, codeCorruptedState, "State of "+name+" is neither an account nor an integer")
+		}
+		if !legacy {
+			continue
+		}
+
+		err = putAccount(stub, account)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		migrated = append(migrated, name)
+	}
+	logger.Infof("Migrated accounts: %v\n", migrated)
+
+	payload, err := json.Marshal(migrated)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
-
-        return shim.Success(nil);
+	return shim.Success(payload)
 }
 
 // Deletes an entity from state
 func (t *SimpleChaincode) delete(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting 1")
+		return errorResponse(statusBadRequest, codeInvalidArgument, "Incorrect number of arguments. Expecting name of the person to delete")
 	}
 
 	A := args[0]
 
+	Avalbytes, err := stub.GetState(A)
+	if err != nil {
+		return shim.Error("Failed to get state")
+	}
+	if Avalbytes == nil {
+		return errorResponse(statusNotFound, codeNotFound, "Entity "+A+" not found")
+	}
+
 	// Delete the key from the state in ledger
-	err := stub.DelState(A)
+	err = stub.DelState(A)
 	if err != nil {
 		return shim.Error("Failed to delete state")
 	}
@@ -137,33 +330,205 @@ func (t *SimpleChaincode) delete(stub shim.ChaincodeStubInterface, args []string
 	return shim.Success(nil)
 }
 
-// Query callback representing the query of a chaincode
+// Query callback representing the query of a chaincode, returns the account document
 func (t *SimpleChaincode) query(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return errorResponse(statusBadRequest, codeInvalidArgument, "Incorrect number of arguments. Expecting name of the person to query")
+	}
 
-	var A string // Entities
-	var err error
+	A := args[0]
+
+	// Get the state from the ledger, legacy balances are returned as accounts without an owner
+	account, resp := getAccount(stub, A)
+	if resp != nil {
+		return *resp
+	}
+
+	jsonResp, err := json.Marshal(account)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	logger.Infof("Query Response:%s\n", jsonResp)
+	return shim.Success(jsonResp)
+}
 
+// Query callback representing a page of all the accounts in key order, args are the page size and an optional bookmark
+func (t *SimpleChaincode) list(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	pageSize, bookmark, resp := parsePage(args)
+	if resp != nil {
+		return *resp
+	}
+
+	iter, metadata, err := stub.GetStateByRangeWithPagination("", "", pageSize, bookmark)
+	if err != nil {
+		return shim.Error("Failed to get state by range: " + err.Error())
+	}
+	page, err := readAccountPage(iter, metadata)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	payload, err := json.Marshal(page)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(payload)
+}
+
+// Query callback representing a page of the accounts matching a CouchDB Mango query, e.g. {"balance":{"$gt":100},"owner.mspId":"Org1MSP"}.
+// args are the query (a full query with "selector" or a bare selector), the page size and an optional bookmark.
+// Only account documents are matched, it requires the CouchDB state database
+func (t *SimpleChaincode) richQuery(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) < 2 {
+		return errorResponse(statusBadRequest, codeInvalidArgument, "Incorrect number of arguments. Expecting query, page size and optional bookmark")
+	}
+	pageSize, bookmark, resp := parsePage(args[1:])
+	if resp != nil {
+		return *resp
+	}
+
+	query, err := accountQuery(args[0])
+	if err != nil {
+		return errorResponse(statusBadRequest, codeInvalidArgument, "Invalid query: "+err.Error())
+	}
+
+	iter, metadata, err := stub.GetQueryResultWithPagination(query, pageSize, bookmark)
+	if err != nil {
+		return errorResponse(statusBadRequest, codeInvalidArgument, "Failed to execute query: "+err.Error())
+	}
+	page, err := readAccountPage(iter, metadata)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	payload, err := json.Marshal(page)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(payload)
+}
+
+// HistoryRecord is one modification of an entity, Value is the state written by the transaction
+type HistoryRecord struct {
+	TxID      string          `json:"txId"`
+	Timestamp time.Time       `json:"timestamp"`
+	Value     json.RawMessage `json:"value"`
+	IsDelete  bool            `json:"isDelete"`
+}
+
+// HistoryPage is a page of the modifications of an entity, oldest first
+type HistoryPage struct {
+	Records []HistoryRecord `json:"records"`
+	HasMore bool            `json:"hasMore"`
+}
+
+// Query callback representing the modifications of an entity, args are the name and an optional offset and limit
+func (t *SimpleChaincode) history(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 && len(args) != 3 {
+		return errorResponse(statusBadRequest, codeInvalidArgument, "Incorrect number of arguments. Expecting name of the entity and optional offset and limit")
+	}
+
+	A := args[0]
+	offset, limit := 0, 0
+	if len(args) == 3 {
+		var err error
+		offset, err = strconv.Atoi(args[1])
+		if err != nil || offset < 0 {
+			return errorResponse(statusBadRequest, codeInvalidArgument, "Invalid offset, expecting a non-negative integer value")
+		}
+		limit, err = strconv.Atoi(args[2])
+		if err != nil || limit <= 0 {
+			return errorResponse(statusBadRequest, codeInvalidArgument, "Invalid limit, expecting a positive integer value")
+		}
+	}
+
+	iter, err := stub.GetHistoryForKey(A)
+	if err != nil {
+		return shim.Error("Failed to get history for " + A)
+	}
+	defer iter.Close()
+
+	page := HistoryPage{Records: []HistoryRecord{}}
+	for i := 0; iter.HasNext(); i++ {
+		if i < offset {
+			if _, err = iter.Next(); err != nil {
+				return shim.Error(err.Error())
+			}
+			continue
+		}
+		if limit > 0 && len(page.Records) == limit {
+			page.HasMore = true
+			break
+		}
+
+		modification, err := iter.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		timestamp, err := ptypes.Timestamp(modification.Timestamp)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		// Deletes carry no value, corrupted values are returned as strings
+		value := json.RawMessage("null")
+		if !modification.IsDelete {
+			value = modification.Value
+			if !json.Valid(value) {
+				value, _ = json.Marshal(string(modification.Value))
+			}
+		}
+		page.Records = append(page.Records, HistoryRecord{TxID: modification.TxId, Timestamp: timestamp, Value: value, IsDelete: modification.IsDelete})
+	}
+
+	payload, err := json.Marshal(page)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(payload)
+}
+
+// Stores every transient entry as a key of the private data collection, values never reach the shared ledger
+func (t *SimpleChaincode) putPrivate(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting name of the person to query")
+		return errorResponse(statusBadRequest, codeInvalidArgument, "Incorrect number of arguments. Expecting name of the collection")
 	}
 
-	A = args[0]
+	collection := args[0]
 
-	// Get the state from the ledger
-	Avalbytes, err := stub.GetState(A)
+	transient, err := stub.GetTransient()
 	if err != nil {
-		jsonResp := "{\"Error\":\"Failed to get state for " + A + "\"}"
-		return shim.Error(jsonResp)
+		return shim.Error("Failed to get transient data")
+	}
+	if len(transient) == 0 {
+		return errorResponse(statusBadRequest, codeInvalidArgument, "Private values must be passed in the transient map")
 	}
 
-	if Avalbytes == nil {
-		jsonResp := "{\"Error\":\"Nil amount for " + A + "\"}"
-		return shim.Error(jsonResp)
+	for key, value := range transient {
+		err = stub.PutPrivateData(collection, key, value)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
 	}
 
-	jsonResp := "{\"Name\":\"" + A + "\",\"Amount\":\"" + string(Avalbytes) + "\"}"
-	logger.Infof("Query Response:%s\n", jsonResp)
-	return shim.Success(Avalbytes)
+	return shim.Success(nil)
+}
+
+// Query callback representing the query of a private data collection
+func (t *SimpleChaincode) getPrivate(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 {
+		return errorResponse(statusBadRequest, codeInvalidArgument, "Incorrect number of arguments. Expecting name of the collection and key to query")
+	}
+
+	value, err := stub.GetPrivateData(args[0], args[1])
+	if err != nil {
+		return shim.Error("Failed to get private data for " + args[1])
+	}
+	if value == nil {
+		return errorResponse(statusNotFound, codeNotFound, "Private data "+args[1]+" not found in "+args[0])
+	}
+
+	return shim.Success(value)
 }
 
 func main() {
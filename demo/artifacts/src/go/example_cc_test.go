@@ -0,0 +1,382 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/msp"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+const testMSPID = "Org1MSP"
+
+// creatorStub returns a fixed creator, MockStub has none
+type creatorStub struct {
+	shim.ChaincodeStubInterface
+	creator []byte
+}
+
+func (stub *creatorStub) GetCreator() ([]byte, error) {
+	return stub.creator, nil
+}
+
+// creatorChaincode runs SimpleChaincode with the transactions submitted by creator
+type creatorChaincode struct {
+	SimpleChaincode
+	creator []byte
+}
+
+func (t *creatorChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return t.SimpleChaincode.Init(&creatorStub{stub, t.creator})
+}
+
+func (t *creatorChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	return t.SimpleChaincode.Invoke(&creatorStub{stub, t.creator})
+}
+
+// attributesOID is the certificate extension holding the attributes issued by the fabric CA
+var attributesOID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+// newCreator serializes an identity of testMSPID with a self-signed certificate carrying attrs
+func newCreator(t *testing.T, attrs map[string]string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "User1@org1.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	if attrs != nil {
+		value, err := json.Marshal(map[string]interface{}{"attrs": attrs})
+		if err != nil {
+			t.Fatal(err)
+		}
+		template.ExtraExtensions = []pkix.Extension{{Id: attributesOID, Value: value}}
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creator, err := proto.Marshal(&msp.SerializedIdentity{
+		Mspid:   testMSPID,
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return creator
+}
+
+// newStub creates a chaincode stub holding the given legacy integer balances
+func newStub(t *testing.T, balances map[string]string) *shim.MockStub {
+	return newStubAs(t, newCreator(t, nil), balances)
+}
+
+// newStubAs creates a chaincode stub whose transactions are submitted by creator
+func newStubAs(t *testing.T, creator []byte, balances map[string]string) *shim.MockStub {
+	stub := shim.NewMockStub("example_cc", &creatorChaincode{creator: creator})
+	stub.MockTransactionStart("seed")
+	for name, balance := range balances {
+		if err := stub.PutState(name, []byte(balance)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stub.MockTransactionEnd("seed")
+	return stub
+}
+
+func invoke(stub *shim.MockStub, args ...string) pb.Response {
+	var input [][]byte
+	for _, arg := range args {
+		input = append(input, []byte(arg))
+	}
+	return stub.MockInvoke("tx", input)
+}
+
+func balanceOf(t *testing.T, stub *shim.MockStub, name string) int {
+	account, resp := getAccount(stub, name)
+	if resp != nil {
+		t.Fatalf("failed to get %s: %s", name, resp.Message)
+	}
+	return account.Balance
+}
+
+func TestMove(t *testing.T) {
+	stub := newStub(t, map[string]string{"a": "100", "b": "200"})
+
+	resp := invoke(stub, "move", "a", "b", "30")
+	if resp.Status != shim.OK {
+		t.Fatalf("move failed: %s", resp.Message)
+	}
+	if balance := balanceOf(t, stub, "a"); balance != 70 {
+		t.Errorf("balance of a = %d, want 70", balance)
+	}
+	if balance := balanceOf(t, stub, "b"); balance != 230 {
+		t.Errorf("balance of b = %d, want 230", balance)
+	}
+
+	// Legacy balances are stored as account documents after the move
+	if value := stub.State["a"]; len(value) == 0 || value[0] != '{' {
+		t.Errorf("state of a = %s, want an account document", value)
+	}
+}
+
+func TestMoveRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		status int32
+		code   string
+	}{
+		{"missing argument", []string{"a", "b"}, statusBadRequest, codeInvalidArgument},
+		{"invalid amount", []string{"a", "b", "ten"}, statusBadRequest, codeInvalidArgument},
+		{"negative amount", []string{"a", "b", "-10"}, statusBadRequest, codeInvalidArgument},
+		{"zero amount", []string{"a", "b", "0"}, statusBadRequest, codeInvalidArgument},
+		{"self transfer", []string{"a", "a", "10"}, statusBadRequest, codeInvalidArgument},
+		{"insufficient funds", []string{"a", "b", "101"}, statusConflict, codeInsufficientFunds},
+		{"unknown payer", []string{"c", "b", "10"}, statusNotFound, codeNotFound},
+		{"unknown payee", []string{"a", "c", "10"}, statusNotFound, codeNotFound},
+		{"corrupted state", []string{"a", "broken", "10"}, statusConflict, codeCorruptedState},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newStub(t, map[string]string{"a": "100", "b": "200", "broken": "not a balance"})
+
+			resp := invoke(stub, append([]string{"move"}, test.args...)...)
+			if resp.Status != test.status {
+				t.Errorf("status = %d, want %d (%s)", resp.Status, test.status, resp.Message)
+			}
+			if !strings.HasPrefix(resp.Message, test.code+": ") {
+				t.Errorf("message = %q, want code %s", resp.Message, test.code)
+			}
+
+			// A rejected move leaves the balances untouched
+			if balance := balanceOf(t, stub, "a"); balance != 100 {
+				t.Errorf("balance of a = %d, want 100", balance)
+			}
+			if balance := balanceOf(t, stub, "b"); balance != 200 {
+				t.Errorf("balance of b = %d, want 200", balance)
+			}
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	stub := newStub(t, nil)
+
+	if resp := invoke(stub, "open", "a", "100"); resp.Status != shim.OK {
+		t.Fatalf("open failed: %s", resp.Message)
+	}
+	if resp := invoke(stub, "create", "b", "0", "USD"); resp.Status != shim.OK {
+		t.Fatalf("create failed: %s", resp.Message)
+	}
+
+	a, resp := getAccount(stub, "a")
+	if resp != nil {
+		t.Fatalf("failed to get a: %s", resp.Message)
+	}
+	if a.Balance != 100 || a.Currency != defaultCurrency {
+		t.Errorf("a = %d %s, want 100 %s", a.Balance, a.Currency, defaultCurrency)
+	}
+	if a.Owner.MSPID != testMSPID || a.Owner.ID == "" {
+		t.Errorf("owner of a = %+v, want the creator", a.Owner)
+	}
+	if a.CreatedAt.IsZero() {
+		t.Error("createdAt of a is not set")
+	}
+	if balance := balanceOf(t, stub, "b"); balance != 0 {
+		t.Errorf("balance of b = %d, want 0", balance)
+	}
+}
+
+func TestOpenRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		status int32
+		code   string
+	}{
+		{"duplicate account", []string{"a", "10"}, statusConflict, codeAlreadyExists},
+		{"negative amount", []string{"b", "-10"}, statusBadRequest, codeInvalidArgument},
+		{"invalid amount", []string{"b", "ten"}, statusBadRequest, codeInvalidArgument},
+		{"missing amount", []string{"b"}, statusBadRequest, codeInvalidArgument},
+		{"empty name", []string{"", "10"}, statusBadRequest, codeInvalidArgument},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newStub(t, map[string]string{"a": "100"})
+
+			resp := invoke(stub, append([]string{"open"}, test.args...)...)
+			if resp.Status != test.status {
+				t.Errorf("status = %d, want %d (%s)", resp.Status, test.status, resp.Message)
+			}
+			if !strings.HasPrefix(resp.Message, test.code+": ") {
+				t.Errorf("message = %q, want code %s", resp.Message, test.code)
+			}
+
+			// An existing account is never overwritten
+			if balance := balanceOf(t, stub, "a"); balance != 100 {
+				t.Errorf("balance of a = %d, want 100", balance)
+			}
+		})
+	}
+}
+
+func TestInit(t *testing.T) {
+	stub := newStub(t, nil)
+
+	resp := stub.MockInit("init", [][]byte{[]byte("init"), []byte("a"), []byte("100"), []byte("b"), []byte("200")})
+	if resp.Status != shim.OK {
+		t.Fatalf("init failed: %s", resp.Message)
+	}
+	if balance := balanceOf(t, stub, "a"); balance != 100 {
+		t.Errorf("balance of a = %d, want 100", balance)
+	}
+	if balance := balanceOf(t, stub, "b"); balance != 200 {
+		t.Errorf("balance of b = %d, want 200", balance)
+	}
+
+	for _, args := range [][]string{{"a"}, {"c", "-1"}, {"c", "1", "c", "2"}} {
+		input := [][]byte{[]byte("init")}
+		for _, arg := range args {
+			input = append(input, []byte(arg))
+		}
+		if resp := newStub(t, nil).MockInit("init", input); resp.Status == shim.OK {
+			t.Errorf("init with %v succeeded, want an error", args)
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	stub := newStub(t, map[string]string{"a": "100"})
+
+	if resp := invoke(stub, "delete", "a"); resp.Status != shim.OK {
+		t.Fatalf("delete failed: %s", resp.Message)
+	}
+	if _, ok := stub.State["a"]; ok {
+		t.Error("a should have been deleted")
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		status int32
+		code   string
+	}{
+		{"unknown entity", []string{"a"}, statusNotFound, codeNotFound},
+		{"missing argument", nil, statusBadRequest, codeInvalidArgument},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := invoke(stub, append([]string{"delete"}, test.args...)...)
+			if resp.Status != test.status {
+				t.Errorf("status = %d, want %d (%s)", resp.Status, test.status, resp.Message)
+			}
+			if !strings.HasPrefix(resp.Message, test.code+": ") {
+				t.Errorf("message = %q, want code %s", resp.Message, test.code)
+			}
+		})
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	stub := newStubAs(t, newCreator(t, map[string]string{"admin": "true"}), map[string]string{"a": "100"})
+	if resp := invoke(stub, "open", "b", "200"); resp.Status != shim.OK {
+		t.Fatalf("open failed: %s", resp.Message)
+	}
+
+	resp := invoke(stub, "migrate")
+	if resp.Status != shim.OK {
+		t.Fatalf("migrate failed: %s", resp.Message)
+	}
+	if string(resp.Payload) != `["a"]` {
+		t.Errorf("migrated = %s, want [\"a\"]", resp.Payload)
+	}
+
+	// The admin running the migration doesn't become the owner
+	a, errResp := getAccount(stub, "a")
+	if errResp != nil {
+		t.Fatalf("failed to get a: %s", errResp.Message)
+	}
+	if a.Owner != (Owner{}) {
+		t.Errorf("owner of a = %+v, want none", a.Owner)
+	}
+	if value := stub.State["a"]; len(value) == 0 || value[0] != '{' {
+		t.Errorf("state of a = %s, want an account document", value)
+	}
+}
+
+func TestMigrateRequiresAdmin(t *testing.T) {
+	for name, attrs := range map[string]map[string]string{"no attributes": nil, "not an admin": {"admin": "false"}} {
+		t.Run(name, func(t *testing.T) {
+			stub := newStubAs(t, newCreator(t, attrs), map[string]string{"a": "100"})
+
+			resp := invoke(stub, "migrate", "a")
+			if resp.Status != statusForbidden {
+				t.Errorf("status = %d, want %d (%s)", resp.Status, statusForbidden, resp.Message)
+			}
+			if !strings.HasPrefix(resp.Message, codePermissionDenied+": ") {
+				t.Errorf("message = %q, want code %s", resp.Message, codePermissionDenied)
+			}
+			if value := stub.State["a"]; string(value) != "100" {
+				t.Errorf("state of a = %s, want the legacy balance", value)
+			}
+		})
+	}
+}
+
+func TestInvokeUnknownFunction(t *testing.T) {
+	stub := newStub(t, nil)
+
+	resp := invoke(stub, "transfer")
+	if resp.Status != shim.ERROR {
+		t.Fatalf("status = %d, want %d", resp.Status, shim.ERROR)
+	}
+	if !strings.Contains(resp.Message, "'open'") || !strings.Contains(resp.Message, "But got: transfer") {
+		t.Errorf("message = %q, want the unknown function", resp.Message)
+	}
+}
+
+func TestPrivateDataRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		status int32
+		code   string
+	}{
+		{"put without collection", []string{"putPrivate"}, statusBadRequest, codeInvalidArgument},
+		{"put without transient data", []string{"putPrivate", "collectionOrg1"}, statusBadRequest, codeInvalidArgument},
+		{"get without key", []string{"getPrivate", "collectionOrg1"}, statusBadRequest, codeInvalidArgument},
+		{"get unknown key", []string{"getPrivate", "collectionOrg1", "a"}, statusNotFound, codeNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := invoke(newStub(t, nil), test.args...)
+			if resp.Status != test.status {
+				t.Errorf("status = %d, want %d (%s)", resp.Status, test.status, resp.Message)
+			}
+			if !strings.HasPrefix(resp.Message, test.code+": ") {
+				t.Errorf("message = %q, want code %s", resp.Message, test.code)
+			}
+		})
+	}
+}
@@ -1,36 +1,175 @@
 package main
 
 import (
+	"time"
+
+	"bcfish.cn/demo/web/blockchain"
+	"bcfish.cn/demo/web/controller"
 	"bcfish.cn/demo/web/middleware"
-	"fmt"
+	"bcfish.cn/demo/web/webhook"
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
+// requestTimeout 单个请求处理时间的上限, 一个请求可能包含多次 Fabric 调用;
+// 每次调用的超时仍取 config.yaml 中的配置(不超过请求剩余的时间)
+const requestTimeout = 3 * time.Minute
+
 func main() {
 
+	// 日志, fabric 操作与请求日志共用
+	logger := logrus.New()
+	middleware.Logger = logger
+
 	// 初始化fabric Sdk
-	fabricSetup := middleware.GetFabricSetupInstance()
+	fabricSetup, err := middleware.GetFabricSetupInstance()
+	if err != nil {
+		logger.WithError(err).Error("Unable to create the Fabric setup")
+		return
+	}
 	if err := fabricSetup.Initialize();err != nil {
-		fmt.Printf("Unable to initialize the Fabric SDK: %v\n", err)
+		logger.WithError(err).Error("Unable to initialize the Fabric SDK")
 		return
 	}
+	defer fabricSetup.Close()
 
 	// 安装example链码
 	exampleFabricSetup, err := middleware.InitExampleCC(fabricSetup)
 	if err != nil {
-		fmt.Printf("初始化失败: %v\n", err)
+		logger.WithError(err).Error("初始化失败")
+		return
+	}
+
+	// 本地区块索引
+	indexer, err := blockchain.NewIndexer(fabricSetup, "index.db")
+	if err != nil {
+		logger.WithError(err).Error("Unable to open block index")
+		return
+	}
+	defer indexer.Close()
+	if err := indexer.Start(); err != nil {
+		logger.WithError(err).Error("Unable to start block indexer")
 		return
 	}
 
-	fmt.Println(exampleFabricSetup)
+	// 事件分发
+	eventHub, err := blockchain.NewEventHub(fabricSetup)
+	if err != nil {
+		logger.WithError(err).Error("Unable to create event hub")
+		return
+	}
+	defer eventHub.Close()
+
+	// webhook 推送
+	dispatcher, err := webhook.NewDispatcher(fabricSetup, "webhook.db", webhook.Config{Logger: logger})
+	if err != nil {
+		logger.WithError(err).Error("Unable to open webhook store")
+		return
+	}
+	defer dispatcher.Close()
+	if err := dispatcher.Start(eventHub); err != nil {
+		logger.WithError(err).Error("Unable to start webhook dispatcher")
+		return
+	}
 
-	r := gin.Default()
+	r := gin.New()
+	r.Use(gin.Recovery(), middleware.RequestLogger())
 	r.GET("/ping", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"message": "pong",
 		})
 	})
+
+	// 登录
+	auth := &controller.Auth{Setup: fabricSetup}
+	r.POST("/auth/login", auth.Login)
+
+	// 以下接口需要登录, 链码调用以登录用户身份提交, 客户端断开或超时后中止调用
+	api := r.Group("/", middleware.Auth(), middleware.RequestTimeout(requestTimeout))
+
+	// 事件推送为长连接, 不设超时
+	stream := r.Group("/", middleware.Auth())
+
+	// 以下接口需要组织管理员角色
+	admin := api.Group("/", middleware.RequireRole(middleware.RoleAdmin))
+
+	// example_cc 链码接口
+	exampleCC := &controller.ExampleCC{Setup: exampleFabricSetup}
+	api.POST("/cc/example_cc/move", exampleCC.Move)
+	api.POST("/cc/example_cc/accounts", exampleCC.OpenAccount)
+	api.GET("/cc/example_cc/accounts", exampleCC.ListAccounts)
+	api.POST("/cc/example_cc/accounts/query", exampleCC.QueryAccounts)
+	admin.POST("/cc/example_cc/accounts/migrate", exampleCC.MigrateAccounts)
+	api.GET("/cc/example_cc/accounts/:name", exampleCC.Account)
+	api.GET("/cc/example_cc/accounts/:name/history", exampleCC.AccountHistory)
+	api.DELETE("/cc/example_cc/accounts/:name", exampleCC.DeleteAccount)
+	api.POST("/cc/example_cc/private/:collection", exampleCC.PutPrivate)
+	api.GET("/cc/example_cc/private/:collection/:key", exampleCC.GetPrivate)
+
+	// 通用链码网关
+	gateway := &controller.Gateway{Setup: fabricSetup}
+	api.POST("/channels/:channel/chaincodes/:cc/invoke", gateway.Invoke)
+	api.POST("/channels/:channel/chaincodes/:cc/query", gateway.Query)
+
+	// 区块浏览
+	ledger := &controller.Ledger{Setup: fabricSetup}
+	api.GET("/ledger/info", ledger.Info)
+	api.GET("/ledger/blocks/:number", ledger.Block)
+	api.GET("/ledger/blockhash/:hash", ledger.BlockByHash)
+	api.GET("/ledger/transactions/:txid", ledger.Transaction)
+	api.GET("/ledger/transactions/:txid/block", ledger.BlockByTxID)
+	api.GET("/channels/:channel/ledger/info", ledger.Info)
+	api.GET("/channels/:channel/ledger/blocks/:number", ledger.Block)
+	api.GET("/channels/:channel/ledger/blockhash/:hash", ledger.BlockByHash)
+	api.GET("/channels/:channel/ledger/transactions/:txid", ledger.Transaction)
+	api.GET("/channels/:channel/ledger/transactions/:txid/block", ledger.BlockByTxID)
+
+	// 通道管理
+	channels := &controller.Channels{Setup: fabricSetup}
+	api.GET("/channels", channels.List)
+	admin.POST("/channels", channels.Create)
+	admin.POST("/channels/:channel/anchor-peers", channels.AnchorPeers)
+	admin.GET("/channels/:channel/config", channels.Config)
+	admin.POST("/channels/:channel/config", channels.UpdateConfig)
+	admin.POST("/channels/:channel/config/sign", channels.SignConfig)
+	admin.POST("/channels/:channel/config/submit", channels.SubmitConfig)
+
+	// 链码生命周期
+	lifecycle := &controller.Lifecycle{Setup: fabricSetup}
+	admin.POST("/lifecycle/install", lifecycle.Install)
+	admin.GET("/lifecycle/installed", lifecycle.Installed)
+	admin.GET("/channels/:channel/lifecycle/chaincodes", lifecycle.Instantiated)
+	admin.POST("/channels/:channel/lifecycle/instantiate", lifecycle.Instantiate)
+	admin.POST("/channels/:channel/lifecycle/upgrade", lifecycle.Upgrade)
+
+	// 本地索引查询
+	index := &controller.Indexer{Indexer: indexer}
+	api.GET("/index/height", index.Height)
+	api.GET("/index/chaincodes/:cc/keys/:key/transactions", index.TransactionsByKey)
+	api.GET("/index/creators/:msp/transactions", index.TransactionsByCreator)
+	api.GET("/index/transactions", index.TransactionsByTime)
+
+	// 事件推送
+	events := &controller.Events{Hub: eventHub}
+	stream.GET("/events/chaincode/:cc", events.Chaincode)
+	stream.GET("/events/blocks", events.Blocks)
+
+	// webhook 订阅
+	hooks := &controller.Webhook{Dispatcher: dispatcher}
+	admin.POST("/webhooks", hooks.Subscribe)
+	admin.GET("/webhooks", hooks.List)
+	admin.DELETE("/webhooks/:id", hooks.Unsubscribe)
+	admin.GET("/webhooks/:id/dead-letters", hooks.DeadLetters)
+
+	// CA用户管理
+	identity := &controller.Identity{Setup: fabricSetup}
+	admin.POST("/identities", identity.Register)
+	admin.GET("/identities", identity.List)
+	admin.GET("/identities/:id", identity.Get)
+	admin.POST("/identities/:id/enroll", identity.Enroll)
+	admin.POST("/identities/:id/reenroll", identity.Reenroll)
+	admin.DELETE("/identities/:id", identity.Revoke)
+
 	r.Run() // listen and serve on 0.0.0.0:8080
 
 }
-
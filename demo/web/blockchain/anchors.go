@@ -0,0 +1,158 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/ledger"
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/resmgmt"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/msp"
+	"github.com/hyperledger/fabric-sdk-go/pkg/fabsdk"
+	cb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// 锚节点更新结果
+const (
+	AnchorPeersUpdated        = "updated"
+	AnchorPeersAlreadyApplied = "already applied"
+	AnchorPeersSkipped        = "skipped"
+)
+
+// AnchorPeerResult 组织在通道上的锚节点更新结果
+type AnchorPeerResult struct {
+	Org         string   `json:"org"`
+	Channel     string   `json:"channel"`
+	Status      string   `json:"status"`
+	AnchorPeers []string `json:"anchor_peers"`
+}
+
+// orgChannelConfig 以组织管理员身份从组织的peer查询通道当前的配置
+func (setup *FabricSetup) orgChannelConfig(ctx context.Context, channelID string, org Org) (*cb.Config, error) {
+	client, err := ledger.New(setup.Util.sdk.ChannelContext(channelID, fabsdk.WithUser(org.Admin), fabsdk.WithOrg(org.Name)))
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create new ledger client of "+org.Name)
+	}
+	config, _, err := (&ChannelLedger{ChannelID: channelID, client: client, ctx: ctx}).QueryConfigBlock()
+	return config, err
+}
+
+// orgGroup 返回通道配置中组织的配置组及其当前锚节点
+func orgGroup(config *cb.Config, org Org) (*cb.ConfigGroup, []string, error) {
+	if config.ChannelGroup == nil {
+		return nil, nil, errors.New("no channel group included in config")
+	}
+	application, ok := config.ChannelGroup.Groups[configGroupApplication]
+	if !ok {
+		return nil, nil, errors.New("no application group included in config")
+	}
+	for name, group := range application.Groups {
+		rendered, err := renderOrg(name, group)
+		if err != nil {
+			return nil, nil, err
+		}
+		if rendered.MspID == org.MspID {
+			return group, rendered.AnchorPeers, nil
+		}
+	}
+	return nil, nil, errors.Errorf("org %s is not a member of the channel", org.MspID)
+}
+
+func samePeers(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// UpdateAnchorPeers 根据通道当前配置为各组织计算锚节点更新(与 configtxgen -outputAnchorPeersUpdate 等价),
+// 以组织管理员身份签名提交, 通道配置中已是 ChannelConfig.AnchorPeers 的组织跳过
+func (setup *FabricSetup) UpdateAnchorPeers(channelConfig ChannelConfig) ([]AnchorPeerResult, error) {
+	return setup.UpdateAnchorPeersContext(context.Background(), channelConfig)
+}
+
+// UpdateAnchorPeersContext 逐个组织更新锚节点
+func (setup *FabricSetup) UpdateAnchorPeersContext(ctx context.Context, channelConfig ChannelConfig) ([]AnchorPeerResult, error) {
+	if err := setup.requireState(StateSDKCreated); err != nil {
+		return nil, err
+	}
+
+	var results []AnchorPeerResult
+	for _, org := range setup.AllOrgs() {
+		result := AnchorPeerResult{Org: org.Name, Channel: channelConfig.ID}
+
+		original, err := setup.orgChannelConfig(ctx, channelConfig.ID, org)
+		if err != nil {
+			return results, err
+		}
+		_, current, err := orgGroup(original, org)
+		if err != nil {
+			return results, err
+		}
+
+		wanted := channelConfig.AnchorPeers[org.Name]
+		if len(wanted) == 0 {
+			setup.log().WithFields(logrus.Fields{LogFieldOrg: org.Name, LogFieldChannel: channelConfig.ID}).Warn("No anchor peers configured")
+			result.Status = AnchorPeersSkipped
+			result.AnchorPeers = current
+			results = append(results, result)
+			continue
+		}
+		if samePeers(current, wanted) {
+			result.Status = AnchorPeersAlreadyApplied
+			result.AnchorPeers = current
+			results = append(results, result)
+			continue
+		}
+
+		// Only the org's AnchorPeers value changes, so the update needs just this org's admin
+		updated := proto.Clone(original).(*cb.Config)
+		group, _, err := orgGroup(updated, org)
+		if err != nil {
+			return results, err
+		}
+		if err := setAnchorPeers(group, wanted); err != nil {
+			return results, errors.WithMessage(err, org.Name)
+		}
+		configUpdate, err := ComputeConfigUpdate(channelConfig.ID, original, updated)
+		if err != nil {
+			return results, err
+		}
+		envelope, err := configUpdateEnvelope(channelConfig.ID, configUpdate)
+		if err != nil {
+			return results, err
+		}
+
+		adminIdentity, err := setup.orgAdminIdentity(org)
+		if err != nil {
+			return results, err
+		}
+
+		req := resmgmt.SaveChannelRequest{ChannelID: channelConfig.ID, ChannelConfig: bytes.NewReader(envelope), SigningIdentities: []msp.SigningIdentity{adminIdentity}}
+		txID, err := setup.Util.admins[org.Name].SaveChannel(req, resmgmtOptions(ctx, resmgmt.WithOrdererEndpoint(org.OrderID))...)
+		if err != nil {
+			return results, errors.WithMessage(err, "failed to update anchor peers of "+org.Name)
+		}
+		if txID.TransactionID == "" {
+			return results, errors.New("failed to update anchor peers of " + org.Name + ": no transaction ID")
+		}
+		setup.log().WithFields(logrus.Fields{LogFieldOrg: org.Name, LogFieldChannel: channelConfig.ID, LogFieldTxID: txID.TransactionID}).Info("Anchor peers updated")
+
+		result.Status = AnchorPeersUpdated
+		result.AnchorPeers = wanted
+		results = append(results, result)
+	}
+	return results, nil
+}
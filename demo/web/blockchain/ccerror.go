@@ -0,0 +1,73 @@
+package blockchain
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/multi"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/status"
+	"github.com/pkg/errors"
+)
+
+// 链码业务错误码, 链码失败响应的 Message 形如 "CODE: 描述"
+const (
+	CodeInvalidArgument   = "INVALID_ARGUMENT"
+	CodeNotFound          = "NOT_FOUND"
+	CodeInsufficientFunds = "INSUFFICIENT_FUNDS"
+	CodeCorruptedState    = "CORRUPTED_STATE"
+	CodeAlreadyExists     = "ALREADY_EXISTS"
+	CodePermissionDenied  = "PERMISSION_DENIED"
+)
+
+// chainCodeErrorStatus 错误码对应的http状态码
+var chainCodeErrorStatus = map[string]int{
+	CodeInvalidArgument:   http.StatusBadRequest,
+	CodeNotFound:          http.StatusNotFound,
+	CodeInsufficientFunds: http.StatusConflict,
+	CodeCorruptedState:    http.StatusConflict,
+	CodeAlreadyExists:     http.StatusConflict,
+	CodePermissionDenied:  http.StatusForbidden,
+}
+
+var chainCodeErrorPattern = regexp.MustCompile(`\b([A-Z]+(?:_[A-Z]+)*): ([^\n]*)`)
+
+// ChainCodeError 链码返回的业务错误
+type ChainCodeError struct {
+	Code    string
+	Message string
+}
+
+func (e *ChainCodeError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// HTTPStatus 错误码对应的http状态码
+func (e *ChainCodeError) HTTPStatus() int {
+	return chainCodeErrorStatus[e.Code]
+}
+
+// AsChainCodeError 从链码调用错误中解析业务错误, 不是已知错误码时返回 false
+func AsChainCodeError(err error) (*ChainCodeError, bool) {
+	if err == nil {
+		return nil, false
+	}
+
+	// Every endorser answers with the same chaincode error, the first one is enough
+	cause := errors.Cause(err)
+	if errs, ok := cause.(multi.Errors); ok && len(errs) > 0 {
+		cause = errors.Cause(errs[0])
+	}
+
+	message := cause.Error()
+	if s, ok := status.FromError(cause); ok {
+		message = s.Message
+	}
+
+	// The sdk prefixes the chaincode message with its own description
+	for _, match := range chainCodeErrorPattern.FindAllStringSubmatch(message, -1) {
+		if _, ok := chainCodeErrorStatus[match[1]]; ok {
+			return &ChainCodeError{Code: match[1], Message: match[2]}, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,79 @@
+package blockchain
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/multi"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/status"
+	"github.com/pkg/errors"
+)
+
+func chainCodeStatus(code int32, message string) error {
+	return status.New(status.EndorserServerStatus, code, message, nil)
+}
+
+func TestAsChainCodeError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		code    string
+		message string
+	}{
+		{"nil", nil, "", ""},
+		{"plain error", errors.New("INVALID_ARGUMENT: Transaction amount must be positive"), CodeInvalidArgument, "Transaction amount must be positive"},
+		{"endorser status", chainCodeStatus(409, "INSUFFICIENT_FUNDS: Balance of a is 10, cannot transfer 20"), CodeInsufficientFunds, "Balance of a is 10, cannot transfer 20"},
+		{"wrapped status", errors.WithMessage(chainCodeStatus(404, "NOT_FOUND: Entity c not found"), "failed to move funds"), CodeNotFound, "Entity c not found"},
+		{"endorser errors", multi.Errors{chainCodeStatus(409, "CORRUPTED_STATE: State of b is neither an account nor an integer"), chainCodeStatus(409, "CORRUPTED_STATE: State of b is neither an account nor an integer")}, CodeCorruptedState, "State of b is neither an account nor an integer"},
+		{"already exists", chainCodeStatus(409, "ALREADY_EXISTS: Account a already exists"), CodeAlreadyExists, "Account a already exists"},
+		{"prefixed message", errors.New("Transaction processing failed: NOT_FOUND: Entity c not found\nmore details"), CodeNotFound, "Entity c not found"},
+		{"permission denied", chainCodeStatus(403, "PERMISSION_DENIED: Only admins can migrate accounts"), CodePermissionDenied, "Only admins can migrate accounts"},
+		{"unknown code", chainCodeStatus(500, "UNAVAILABLE: try again"), "", ""},
+		{"no code", errors.New("failed to connect to peer"), "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ccErr, ok := AsChainCodeError(test.err)
+			if test.code == "" {
+				if ok {
+					t.Fatalf("AsChainCodeError() = %v, want no chaincode error", ccErr)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("AsChainCodeError() found no chaincode error in %v", test.err)
+			}
+			if ccErr.Code != test.code {
+				t.Errorf("code = %q, want %q", ccErr.Code, test.code)
+			}
+			if ccErr.Message != test.message {
+				t.Errorf("message = %q, want %q", ccErr.Message, test.message)
+			}
+		})
+	}
+}
+
+func TestChainCodeErrorHTTPStatus(t *testing.T) {
+	tests := []struct {
+		code   string
+		status int
+	}{
+		{CodeInvalidArgument, http.StatusBadRequest},
+		{CodeNotFound, http.StatusNotFound},
+		{CodeInsufficientFunds, http.StatusConflict},
+		{CodeCorruptedState, http.StatusConflict},
+		{CodeAlreadyExists, http.StatusConflict},
+		{CodePermissionDenied, http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		ccErr := &ChainCodeError{Code: test.code, Message: "message"}
+		if status := ccErr.HTTPStatus(); status != test.status {
+			t.Errorf("HTTPStatus() of %s = %d, want %d", test.code, status, test.status)
+		}
+		if ccErr.Error() != test.code+": message" {
+			t.Errorf("Error() = %q", ccErr.Error())
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package blockchain
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// metaInfDir 链码源码目录下的元数据目录, 如 CouchDB 索引 META-INF/statedb/couchdb/indexes
+const metaInfDir = "META-INF"
+
+// repackage 整理 gopackager 生成的链码包: 移除 _test.go 测试文件, 并将链码源码目录下的 META-INF
+// 放到链码包根目录, peer 只从根目录读取元数据, gopackager 打包到 src 下的 META-INF 文件会被移除
+func repackage(code []byte, chainCode ChainCode) ([]byte, error) {
+	gr, err := gzip.NewReader(bytes.NewReader(code))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read ChainCode package")
+	}
+	tr := tar.NewReader(gr)
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+
+	// Copy the source files, leaving out the tests and the metadata packed as source
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read ChainCode package")
+		}
+		if strings.HasSuffix(header.Name, "_test.go") || strings.Contains(header.Name, "/"+metaInfDir+"/") {
+			continue
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, errors.Wrap(err, "failed to write ChainCode package")
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return nil, errors.Wrap(err, "failed to write ChainCode package")
+		}
+	}
+
+	if err := addMetadata(tw, filepath.Join(chainCode.GoPath, "src", chainCode.SrcPath, metaInfDir)); err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to write ChainCode package")
+	}
+	if err := gw.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to write ChainCode package")
+	}
+	return buf.Bytes(), nil
+}
+
+// addMetadata 将 metaPath 目录下的文件写到链码包的 META-INF 下, 目录不存在时不做处理
+func addMetadata(tw *tar.Writer, metaPath string) error {
+	if _, err := os.Stat(metaPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	err := filepath.Walk(metaPath, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(metaPath, file)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		header := &tar.Header{Name: path.Join(metaInfDir, filepath.ToSlash(rel)), Mode: 0100644, Size: int64(len(data))}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to add ChainCode metadata")
+	}
+	return nil
+}
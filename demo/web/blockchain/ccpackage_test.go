@@ -0,0 +1,94 @@
+package blockchain
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func tarGz(t *testing.T, files ...string) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for _, name := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0100644, Size: int64(len(name))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarNames(t *testing.T, code []byte) []string {
+	gr, err := gzip.NewReader(bytes.NewReader(code))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRepackage(t *testing.T) {
+	goPath, err := ioutil.TempDir("", "ccpackage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(goPath)
+
+	indexDir := filepath.Join(goPath, "src", "cc", metaInfDir, "statedb", "couchdb", "indexes")
+	if err := os.MkdirAll(indexDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(indexDir, "owner.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code := tarGz(t,
+		"src/cc/cc.go",
+		"src/cc/cc_test.go",
+		"src/cc/META-INF/statedb/couchdb/indexes/owner.json",
+	)
+	repacked, err := repackage(code, ChainCode{GoPath: goPath, SrcPath: "cc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"META-INF/statedb/couchdb/indexes/owner.json", "src/cc/cc.go"}
+	got := tarNames(t, repacked)
+	if len(got) != len(want) {
+		t.Fatalf("files = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("files = %v, want %v", got, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,658 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/resmgmt"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/msp"
+	"github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/common/cauthdsl"
+	cb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+	mspproto "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/msp"
+	ab "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/orderer"
+	pb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/peer"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// 通道配置中的组与值名称
+const (
+	configGroupApplication  = "Application"
+	configGroupOrderer      = "Orderer"
+	configValueMSP          = "MSP"
+	configValueAnchorPeers  = "AnchorPeers"
+	configValueBatchSize    = "BatchSize"
+	configValueBatchTimeout = "BatchTimeout"
+	configValueOrderers     = "OrdererAddresses"
+	configPolicyAdmins      = "Admins"
+)
+
+// ChannelConfigInfo 通道配置的JSON视图
+//
+// 修改 BatchSize, BatchTimeout, Orgs(增删组织与锚节点) 后可用于计算配置更新, 其余字段只读;
+// 计算更新时只修改给出的字段: 省略的字段保持不变, 给出 Orgs 时为完整的组织列表, 未列出的组织被移除
+type ChannelConfigInfo struct {
+	ChannelID    string            `json:"channel_id"`
+	BlockNumber  uint64            `json:"block_number"`
+	Sequence     uint64            `json:"sequence"`
+	Orderers     []string          `json:"orderers"`
+	BatchSize    *BatchSize        `json:"batch_size,omitempty"`
+	BatchTimeout string            `json:"batch_timeout,omitempty"`
+	Orgs         []OrgConfig       `json:"orgs"`
+	Policies     map[string]string `json:"policies"`
+}
+
+// BatchSize 出块大小
+type BatchSize struct {
+	MaxMessageCount   uint32 `json:"max_message_count"`
+	AbsoluteMaxBytes  uint32 `json:"absolute_max_bytes"`
+	PreferredMaxBytes uint32 `json:"preferred_max_bytes"`
+}
+
+// OrgConfig 应用组织, 新增组织时需提供 MSPConfig(序列化的 msp.MSPConfig), 省略 AnchorPeers 时锚节点不变
+type OrgConfig struct {
+	Name        string   `json:"name"`
+	MspID       string   `json:"msp_id"`
+	AnchorPeers []string `json:"anchor_peers"`
+	MSPConfig   []byte   `json:"msp_config,omitempty"`
+}
+
+// ChannelConfigUpdate 计算得到的配置更新, Envelope 为可直接提交的 CONFIG_UPDATE 交易, Signers 为已签名的组织
+type ChannelConfigUpdate struct {
+	ChannelID string   `json:"channel_id"`
+	Envelope  []byte   `json:"envelope"`
+	Signers   []string `json:"signers"`
+	TxID      string   `json:"tx_id,omitempty"`
+}
+
+// QueryConfigBlock 查询通道最新的配置区块并解析出配置
+func (l *ChannelLedger) QueryConfigBlock() (*cb.Config, uint64, error) {
+	cfg, err := l.client.QueryConfig(ledgerOptions(l.ctx)...)
+	if err != nil {
+		return nil, 0, errors.WithMessage(err, "failed to query channel config")
+	}
+	block, err := l.client.QueryBlock(cfg.BlockNumber(), ledgerOptions(l.ctx)...)
+	if err != nil {
+		return nil, 0, errors.WithMessage(err, "failed to query config block")
+	}
+	if block.Data == nil || len(block.Data.Data) == 0 {
+		return nil, 0, errors.New("config block is empty")
+	}
+
+	env := &cb.Envelope{}
+	if err := proto.Unmarshal(block.Data.Data[0], env); err != nil {
+		return nil, 0, errors.Wrap(err, "failed to unmarshal config envelope")
+	}
+	payload := &cb.Payload{}
+	if err := proto.Unmarshal(env.Payload, payload); err != nil {
+		return nil, 0, errors.Wrap(err, "failed to unmarshal payload")
+	}
+	configEnv := &cb.ConfigEnvelope{}
+	if err := proto.Unmarshal(payload.Data, configEnv); err != nil {
+		return nil, 0, errors.Wrap(err, "failed to unmarshal config")
+	}
+	if configEnv.Config == nil {
+		return nil, 0, errors.New("config block has no config")
+	}
+	return configEnv.Config, cfg.BlockNumber(), nil
+}
+
+// ChannelConfiguration 查询通道配置并转换为JSON视图
+func (setup *FabricSetup) ChannelConfiguration(channelID string) (*ChannelConfigInfo, error) {
+	return setup.ChannelConfigurationContext(context.Background(), channelID)
+}
+
+// ChannelConfigurationContext 查询通道配置并转换为JSON视图
+func (setup *FabricSetup) ChannelConfigurationContext(ctx context.Context, channelID string) (*ChannelConfigInfo, error) {
+	l, err := setup.Ledger(channelID)
+	if err != nil {
+		return nil, err
+	}
+	config, number, err := l.WithContext(ctx).QueryConfigBlock()
+	if err != nil {
+		return nil, err
+	}
+	info, err := RenderChannelConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	info.ChannelID = channelID
+	info.BlockNumber = number
+	return info, nil
+}
+
+// RenderChannelConfig 将通道配置转换为JSON视图
+func RenderChannelConfig(config *cb.Config) (*ChannelConfigInfo, error) {
+	root := config.ChannelGroup
+	if root == nil {
+		return nil, errors.New("no channel group included in config")
+	}
+	info := &ChannelConfigInfo{Sequence: config.Sequence, Orgs: []OrgConfig{}, Policies: make(map[string]string)}
+
+	if value, ok := root.Values[configValueOrderers]; ok {
+		addresses := &cb.OrdererAddresses{}
+		if err := proto.Unmarshal(value.Value, addresses); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal orderer addresses")
+		}
+		info.Orderers = addresses.Addresses
+	}
+
+	if orderer, ok := root.Groups[configGroupOrderer]; ok {
+		if value, ok := orderer.Values[configValueBatchSize]; ok {
+			batchSize := &ab.BatchSize{}
+			if err := proto.Unmarshal(value.Value, batchSize); err != nil {
+				return nil, errors.Wrap(err, "failed to unmarshal batch size")
+			}
+			info.BatchSize = &BatchSize{MaxMessageCount: batchSize.MaxMessageCount, AbsoluteMaxBytes: batchSize.AbsoluteMaxBytes, PreferredMaxBytes: batchSize.PreferredMaxBytes}
+		}
+		if value, ok := orderer.Values[configValueBatchTimeout]; ok {
+			batchTimeout := &ab.BatchTimeout{}
+			if err := proto.Unmarshal(value.Value, batchTimeout); err != nil {
+				return nil, errors.Wrap(err, "failed to unmarshal batch timeout")
+			}
+			info.BatchTimeout = batchTimeout.Timeout
+		}
+	}
+
+	if application, ok := root.Groups[configGroupApplication]; ok {
+		for name, group := range application.Groups {
+			org, err := renderOrg(name, group)
+			if err != nil {
+				return nil, err
+			}
+			info.Orgs = append(info.Orgs, org)
+		}
+		sort.Slice(info.Orgs, func(i, j int) bool { return info.Orgs[i].Name < info.Orgs[j].Name })
+	}
+
+	if err := renderPolicies("/Channel", root, info.Policies); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func renderOrg(name string, group *cb.ConfigGroup) (OrgConfig, error) {
+	org := OrgConfig{Name: name, MspID: name, AnchorPeers: []string{}}
+
+	if value, ok := group.Values[configValueMSP]; ok {
+		mspConfig := &mspproto.MSPConfig{}
+		if err := proto.Unmarshal(value.Value, mspConfig); err != nil {
+			return org, errors.Wrap(err, "failed to unmarshal msp config of "+name)
+		}
+		fabricConfig := &mspproto.FabricMSPConfig{}
+		if err := proto.Unmarshal(mspConfig.Config, fabricConfig); err == nil && fabricConfig.Name != "" {
+			org.MspID = fabricConfig.Name
+		}
+	}
+
+	if value, ok := group.Values[configValueAnchorPeers]; ok {
+		anchors := &pb.AnchorPeers{}
+		if err := proto.Unmarshal(value.Value, anchors); err != nil {
+			return org, errors.Wrap(err, "failed to unmarshal anchor peers of "+name)
+		}
+		for _, anchor := range anchors.AnchorPeers {
+			org.AnchorPeers = append(org.AnchorPeers, net.JoinHostPort(anchor.Host, strconv.Itoa(int(anchor.Port))))
+		}
+	}
+	return org, nil
+}
+
+// renderPolicies 以 /Channel/Application/Admins 形式的路径列出所有策略
+func renderPolicies(path string, group *cb.ConfigGroup, policies map[string]string) error {
+	for name, configPolicy := range group.Policies {
+		if configPolicy.Policy == nil {
+			continue
+		}
+		description, err := describePolicy(configPolicy.Policy)
+		if err != nil {
+			return errors.WithMessage(err, path+"/"+name)
+		}
+		policies[path+"/"+name] = description
+	}
+	for name, child := range group.Groups {
+		if err := renderPolicies(path+"/"+name, child, policies); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func describePolicy(policy *cb.Policy) (string, error) {
+	switch cb.Policy_PolicyType(policy.Type) {
+	case cb.Policy_IMPLICIT_META:
+		meta := &cb.ImplicitMetaPolicy{}
+		if err := proto.Unmarshal(policy.Value, meta); err != nil {
+			return "", errors.Wrap(err, "failed to unmarshal implicit meta policy")
+		}
+		return fmt.Sprintf("IMPLICIT_META(%s %s)", meta.Rule, meta.SubPolicy), nil
+	case cb.Policy_SIGNATURE:
+		envelope := &cb.SignaturePolicyEnvelope{}
+		if err := proto.Unmarshal(policy.Value, envelope); err != nil {
+			return "", errors.Wrap(err, "failed to unmarshal signature policy")
+		}
+		var principals []string
+		for _, principal := range envelope.Identities {
+			role := &mspproto.MSPRole{}
+			if err := proto.Unmarshal(principal.Principal, role); err != nil {
+				continue
+			}
+			principals = append(principals, role.MspIdentifier+"."+strings.ToLower(role.Role.String()))
+		}
+		return fmt.Sprintf("SIGNATURE(%s)", strings.Join(principals, ",")), nil
+	}
+	return cb.Policy_PolicyType(policy.Type).String(), nil
+}
+
+// applyChannelConfig 将JSON视图中给出的可修改字段写回配置
+func applyChannelConfig(config *cb.Config, info *ChannelConfigInfo) error {
+	root := config.ChannelGroup
+	if root == nil {
+		return errors.New("no channel group included in config")
+	}
+
+	if info.BatchSize != nil || info.BatchTimeout != "" {
+		orderer, ok := root.Groups[configGroupOrderer]
+		if !ok {
+			return errors.New("no orderer group included in config")
+		}
+		if err := applyBatchConfig(orderer, info); err != nil {
+			return err
+		}
+	}
+
+	if info.Orgs == nil {
+		return nil
+	}
+	if len(info.Orgs) == 0 {
+		return errors.New("a channel needs at least one org")
+	}
+	application, ok := root.Groups[configGroupApplication]
+	if !ok {
+		return errors.New("no application group included in config")
+	}
+	orgs := make(map[string]OrgConfig)
+	for _, org := range info.Orgs {
+		if org.Name == "" {
+			return errors.New("org name is required")
+		}
+		orgs[org.Name] = org
+	}
+	for name := range application.Groups {
+		if _, ok := orgs[name]; !ok {
+			delete(application.Groups, name)
+		}
+	}
+	for name, org := range orgs {
+		group, ok := application.Groups[name]
+		if !ok {
+			var err error
+			if group, err = newOrgGroup(org); err != nil {
+				return err
+			}
+			application.Groups[name] = group
+		}
+		if org.AnchorPeers == nil {
+			continue
+		}
+		if err := setAnchorPeers(group, org.AnchorPeers); err != nil {
+			return errors.WithMessage(err, name)
+		}
+	}
+	return nil
+}
+
+// applyBatchConfig 校验并写回出块大小与出块超时
+func applyBatchConfig(orderer *cb.ConfigGroup, info *ChannelConfigInfo) error {
+	if info.BatchSize != nil {
+		value, ok := orderer.Values[configValueBatchSize]
+		if !ok {
+			return errors.New("no batch size included in orderer config")
+		}
+		batchSize := &ab.BatchSize{MaxMessageCount: info.BatchSize.MaxMessageCount, AbsoluteMaxBytes: info.BatchSize.AbsoluteMaxBytes, PreferredMaxBytes: info.BatchSize.PreferredMaxBytes}
+		if batchSize.MaxMessageCount == 0 || batchSize.AbsoluteMaxBytes == 0 || batchSize.PreferredMaxBytes == 0 {
+			return errors.New("batch size must be positive")
+		}
+		if batchSize.PreferredMaxBytes > batchSize.AbsoluteMaxBytes {
+			return errors.New("preferred_max_bytes must not exceed absolute_max_bytes")
+		}
+		data, err := proto.Marshal(batchSize)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal batch size")
+		}
+		value.Value = data
+	}
+
+	if info.BatchTimeout != "" {
+		value, ok := orderer.Values[configValueBatchTimeout]
+		if !ok {
+			return errors.New("no batch timeout included in orderer config")
+		}
+		timeout, err := time.ParseDuration(info.BatchTimeout)
+		if err != nil {
+			return errors.Wrap(err, "invalid batch timeout "+info.BatchTimeout)
+		}
+		if timeout <= 0 {
+			return errors.New("batch timeout must be positive")
+		}
+		data, err := proto.Marshal(&ab.BatchTimeout{Timeout: info.BatchTimeout})
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal batch timeout")
+		}
+		value.Value = data
+	}
+	return nil
+}
+
+// newOrgGroup 为新加入的组织创建配置组, 读写策略为组织成员, 管理策略为组织管理员
+func newOrgGroup(org OrgConfig) (*cb.ConfigGroup, error) {
+	if len(org.MSPConfig) == 0 {
+		return nil, errors.Errorf("msp_config is required to add org %s", org.Name)
+	}
+	if err := proto.Unmarshal(org.MSPConfig, &mspproto.MSPConfig{}); err != nil {
+		return nil, errors.Wrap(err, "invalid msp_config of "+org.Name)
+	}
+
+	group := newConfigGroup()
+	group.ModPolicy = configPolicyAdmins
+	group.Values[configValueMSP] = &cb.ConfigValue{Value: org.MSPConfig, ModPolicy: configPolicyAdmins}
+
+	policies := map[string]*cb.SignaturePolicyEnvelope{
+		"Readers":          cauthdsl.SignedByMspMember(org.MspID),
+		"Writers":          cauthdsl.SignedByMspMember(org.MspID),
+		configPolicyAdmins: cauthdsl.SignedByMspAdmin(org.MspID),
+	}
+	for name, envelope := range policies {
+		data, err := proto.Marshal(envelope)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal policy")
+		}
+		group.Policies[name] = &cb.ConfigPolicy{
+			Policy:    &cb.Policy{Type: int32(cb.Policy_SIGNATURE), Value: data},
+			ModPolicy: configPolicyAdmins,
+		}
+	}
+	return group, nil
+}
+
+func setAnchorPeers(group *cb.ConfigGroup, peers []string) error {
+	if len(peers) == 0 {
+		delete(group.Values, configValueAnchorPeers)
+		return nil
+	}
+
+	anchors := &pb.AnchorPeers{}
+	for _, peer := range peers {
+		host, port, err := net.SplitHostPort(peer)
+		if err != nil {
+			return errors.Wrap(err, "invalid anchor peer "+peer)
+		}
+		portNumber, err := strconv.Atoi(port)
+		if err != nil {
+			return errors.Wrap(err, "invalid anchor peer port "+peer)
+		}
+		anchors.AnchorPeers = append(anchors.AnchorPeers, &pb.AnchorPeer{Host: host, Port: int32(portNumber)})
+	}
+	data, err := proto.Marshal(anchors)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal anchor peers")
+	}
+
+	if value, ok := group.Values[configValueAnchorPeers]; ok {
+		value.Value = data
+	} else {
+		group.Values[configValueAnchorPeers] = &cb.ConfigValue{Value: data, ModPolicy: configPolicyAdmins}
+	}
+	return nil
+}
+
+// ComputeChannelConfigUpdate 根据修改后的JSON视图计算通道配置更新, 返回未签名的 CONFIG_UPDATE 交易
+func (setup *FabricSetup) ComputeChannelConfigUpdate(channelID string, info *ChannelConfigInfo) (*ChannelConfigUpdate, error) {
+	return setup.ComputeChannelConfigUpdateContext(context.Background(), channelID, info)
+}
+
+// ComputeChannelConfigUpdateContext 查询当前配置区块并计算配置更新
+func (setup *FabricSetup) ComputeChannelConfigUpdateContext(ctx context.Context, channelID string, info *ChannelConfigInfo) (*ChannelConfigUpdate, error) {
+	l, err := setup.Ledger(channelID)
+	if err != nil {
+		return nil, err
+	}
+	original, _, err := l.WithContext(ctx).QueryConfigBlock()
+	if err != nil {
+		return nil, err
+	}
+	updated := proto.Clone(original).(*cb.Config)
+	if err := applyChannelConfig(updated, info); err != nil {
+		return nil, err
+	}
+
+	configUpdate, err := ComputeConfigUpdate(channelID, original, updated)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := setup.requireOrdererOrg(configUpdate); err != nil {
+		return nil, err
+	}
+	envelope, err := configUpdateEnvelope(channelID, configUpdate)
+	if err != nil {
+		return nil, err
+	}
+	return &ChannelConfigUpdate{ChannelID: channelID, Envelope: envelope}, nil
+}
+
+func configUpdateEnvelope(channelID string, configUpdate *cb.ConfigUpdate) ([]byte, error) {
+	updateBytes, err := proto.Marshal(configUpdate)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal config update")
+	}
+	data, err := proto.Marshal(&cb.ConfigUpdateEnvelope{ConfigUpdate: updateBytes})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal config update envelope")
+	}
+	chdr, err := proto.Marshal(&cb.ChannelHeader{Type: int32(cb.HeaderType_CONFIG_UPDATE), ChannelId: channelID})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal channel header")
+	}
+	payload, err := proto.Marshal(&cb.Payload{Header: &cb.Header{ChannelHeader: chdr}, Data: data})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal payload")
+	}
+	envelope, err := proto.Marshal(&cb.Envelope{Payload: payload})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal envelope")
+	}
+	return envelope, nil
+}
+
+// decodeConfigUpdate 从 CONFIG_UPDATE 交易中解析出配置更新
+func decodeConfigUpdate(envelope []byte) (*cb.ConfigUpdate, error) {
+	env := &cb.Envelope{}
+	if err := proto.Unmarshal(envelope, env); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal envelope")
+	}
+	payload := &cb.Payload{}
+	if err := proto.Unmarshal(env.Payload, payload); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal payload")
+	}
+	updateEnv := &cb.ConfigUpdateEnvelope{}
+	if err := proto.Unmarshal(payload.Data, updateEnv); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal config update envelope")
+	}
+	configUpdate := &cb.ConfigUpdate{}
+	if err := proto.Unmarshal(updateEnv.ConfigUpdate, configUpdate); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal config update")
+	}
+	return configUpdate, nil
+}
+
+// modifiesOrderer 判断配置更新是否修改了排序组
+func modifiesOrderer(configUpdate *cb.ConfigUpdate) bool {
+	if configUpdate.WriteSet == nil {
+		return false
+	}
+	written, ok := configUpdate.WriteSet.Groups[configGroupOrderer]
+	if !ok {
+		return false
+	}
+	// An unchanged group is listed with the same content in the read set
+	var read *cb.ConfigGroup
+	if configUpdate.ReadSet != nil {
+		read = configUpdate.ReadSet.Groups[configGroupOrderer]
+	}
+	return read == nil || !proto.Equal(read, written)
+}
+
+// requireOrdererOrg 修改排序组的更新需要排序组织管理员签名
+func (setup *FabricSetup) requireOrdererOrg(configUpdate *cb.ConfigUpdate) (bool, error) {
+	if !modifiesOrderer(configUpdate) {
+		return false, nil
+	}
+	if setup.OrdererOrg.Name == "" {
+		return true, errors.New("config update modifies the orderer group, but no orderer org is configured to sign it")
+	}
+	return true, nil
+}
+
+// configApprovals 记录各配置更新交易已同意签名的组织, 以 通道+交易摘要 为键
+type configApprovals struct {
+	mu   sync.Mutex
+	orgs map[string][]string
+}
+
+func approvalKey(update *ChannelConfigUpdate) string {
+	digest := sha256.Sum256(update.Envelope)
+	return update.ChannelID + "\x00" + hex.EncodeToString(digest[:])
+}
+
+// approve 记录 org 的签名, 返回已签名的组织
+func (approvals *configApprovals) approve(update *ChannelConfigUpdate, org string) []string {
+	approvals.mu.Lock()
+	defer approvals.mu.Unlock()
+
+	if approvals.orgs == nil {
+		approvals.orgs = make(map[string][]string)
+	}
+	key := approvalKey(update)
+	for _, name := range approvals.orgs[key] {
+		if name == org {
+			return append([]string(nil), approvals.orgs[key]...)
+		}
+	}
+	approvals.orgs[key] = append(approvals.orgs[key], org)
+	return append([]string(nil), approvals.orgs[key]...)
+}
+
+func (approvals *configApprovals) remove(update *ChannelConfigUpdate) {
+	approvals.mu.Lock()
+	defer approvals.mu.Unlock()
+
+	delete(approvals.orgs, approvalKey(update))
+}
+
+// checkConfigUpdate 解析配置更新交易, 并校验其通道与 update.ChannelID 一致
+func checkConfigUpdate(update *ChannelConfigUpdate) (*cb.ConfigUpdate, error) {
+	configUpdate, err := decodeConfigUpdate(update.Envelope)
+	if err != nil {
+		return nil, err
+	}
+	if configUpdate.ChannelId != update.ChannelID {
+		return nil, errors.Errorf("config update is for channel %s, not %s", configUpdate.ChannelId, update.ChannelID)
+	}
+	return configUpdate, nil
+}
+
+// signerOrg 返回名为 name 的应用组织或排序组织, name 为空时为 Org
+func (setup *FabricSetup) signerOrg(name string) (Org, error) {
+	if name == "" {
+		return setup.Org, nil
+	}
+	if setup.OrdererOrg.Name != "" && name == setup.OrdererOrg.Name {
+		return setup.OrdererOrg, nil
+	}
+	orgs, err := setup.selectOrgs([]string{name})
+	if err != nil {
+		return Org{}, err
+	}
+	return orgs[0], nil
+}
+
+// SignChannelConfigUpdate 记录 org 组织管理员对配置更新的签名, 返回的 Signers 为已签名的组织;
+// 各组织管理员分别签名后由 SubmitChannelConfigUpdate 提交
+func (setup *FabricSetup) SignChannelConfigUpdate(update *ChannelConfigUpdate, org string) (*ChannelConfigUpdate, error) {
+	if err := setup.requireState(StateChannelJoined); err != nil {
+		return nil, err
+	}
+	signer, err := setup.signerOrg(org)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := checkConfigUpdate(update); err != nil {
+		return nil, err
+	}
+
+	update.Signers = setup.Util.approvals.approve(update, signer.Name)
+	return update, nil
+}
+
+// SubmitChannelConfigUpdate 以 org 组织管理员签名并提交配置更新, 之前通过 SignChannelConfigUpdate 签名的组织一并签名;
+// 修改了排序组时还需要 OrdererOrg 管理员的签名
+func (setup *FabricSetup) SubmitChannelConfigUpdate(update *ChannelConfigUpdate, org string) (*ChannelConfigUpdate, error) {
+	return setup.SubmitChannelConfigUpdateContext(context.Background(), update, org)
+}
+
+// SubmitChannelConfigUpdateContext 签名并向排序节点提交配置更新
+func (setup *FabricSetup) SubmitChannelConfigUpdateContext(ctx context.Context, update *ChannelConfigUpdate, org string) (*ChannelConfigUpdate, error) {
+	update, err := setup.SignChannelConfigUpdate(update, org)
+	if err != nil {
+		return nil, err
+	}
+	configUpdate, err := checkConfigUpdate(update)
+	if err != nil {
+		return nil, err
+	}
+
+	// Orderer settings are governed by the orderer org, the update can't pass without its admin
+	orderer, err := setup.requireOrdererOrg(configUpdate)
+	if err != nil {
+		return nil, err
+	}
+	var identities []msp.SigningIdentity
+	ordererSigned := false
+	for _, name := range update.Signers {
+		signer, err := setup.signerOrg(name)
+		if err != nil {
+			return nil, err
+		}
+		identity, err := setup.orgAdminIdentity(signer)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+		ordererSigned = ordererSigned || name == setup.OrdererOrg.Name
+	}
+	if orderer && !ordererSigned {
+		return nil, errors.Errorf("config update modifies the orderer group and must be signed by %s", setup.OrdererOrg.Name)
+	}
+
+	req := resmgmt.SaveChannelRequest{ChannelID: update.ChannelID, ChannelConfig: bytes.NewReader(update.Envelope), SigningIdentities: identities}
+	txID, err := setup.Util.admin.SaveChannel(req, resmgmtOptions(ctx, resmgmt.WithOrdererEndpoint(setup.Org.OrderID))...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to submit channel config update")
+	}
+	if txID.TransactionID == "" {
+		return nil, errors.New("failed to submit channel config update: no transaction ID")
+	}
+	setup.Util.approvals.remove(update)
+	update.TxID = string(txID.TransactionID)
+	setup.log().WithFields(logrus.Fields{LogFieldChannel: update.ChannelID, LogFieldTxID: update.TxID, "signers": update.Signers}).Info("Channel config updated")
+	return update, nil
+}
@@ -0,0 +1,48 @@
+package blockchain
+
+import (
+	"reflect"
+	"testing"
+
+	cb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+)
+
+func TestCheckConfigUpdate(t *testing.T) {
+	envelope, err := configUpdateEnvelope("mychannel", &cb.ConfigUpdate{ChannelId: "mychannel"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := checkConfigUpdate(&ChannelConfigUpdate{ChannelID: "mychannel", Envelope: envelope}); err != nil {
+		t.Errorf("checkConfigUpdate(): %v", err)
+	}
+	if _, err := checkConfigUpdate(&ChannelConfigUpdate{ChannelID: "otherchannel", Envelope: envelope}); err == nil {
+		t.Error("an update of another channel should be rejected")
+	}
+	if _, err := checkConfigUpdate(&ChannelConfigUpdate{ChannelID: "mychannel", Envelope: []byte("not an envelope")}); err == nil {
+		t.Error("an invalid envelope should be rejected")
+	}
+}
+
+func TestConfigApprovals(t *testing.T) {
+	approvals := &configApprovals{}
+	update := &ChannelConfigUpdate{ChannelID: "mychannel", Envelope: []byte("update")}
+	other := &ChannelConfigUpdate{ChannelID: "mychannel", Envelope: []byte("other update")}
+
+	approvals.approve(update, "org1")
+	if got := approvals.approve(update, "org2"); !reflect.DeepEqual(got, []string{"org1", "org2"}) {
+		t.Errorf("signers = %v, want [org1 org2]", got)
+	}
+	// Signing twice doesn't add the org again
+	if got := approvals.approve(update, "org1"); !reflect.DeepEqual(got, []string{"org1", "org2"}) {
+		t.Errorf("signers = %v, want [org1 org2]", got)
+	}
+	if got := approvals.approve(other, "org2"); !reflect.DeepEqual(got, []string{"org2"}) {
+		t.Errorf("signers of another update = %v, want [org2]", got)
+	}
+
+	approvals.remove(update)
+	if got := approvals.approve(update, "org2"); !reflect.DeepEqual(got, []string{"org2"}) {
+		t.Errorf("signers after removal = %v, want [org2]", got)
+	}
+}
@@ -0,0 +1,294 @@
+package blockchain
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/event"
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/ledger"
+	mspclient "github.com/hyperledger/fabric-sdk-go/pkg/client/msp"
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/resmgmt"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/multi"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/retry"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/status"
+	fabApi "github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/msp"
+	"github.com/hyperledger/fabric-sdk-go/pkg/fabsdk"
+	cb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// channelClients 单个通道上以 Org.User 身份创建的客户端, 首次使用时创建
+type channelClients struct {
+	channel *channel.Client
+	event   *event.Client
+	ledger  *ledger.Client
+}
+
+// channelManager 跟踪已加入的通道及其客户端
+type channelManager struct {
+	mu       sync.Mutex
+	joined   map[string]ChannelConfig
+	channels map[string]*channelClients
+}
+
+// PeerChannels peer 已加入的通道
+type PeerChannels struct {
+	Org      string   `json:"org"`
+	Peer     string   `json:"peer"`
+	Channels []string `json:"channels"`
+}
+
+// AllChannels 返回所有需要管理的通道, ChannelConfig 为默认通道
+func (setup *FabricSetup) AllChannels() []ChannelConfig {
+	return append([]ChannelConfig{setup.ChannelConfig}, setup.Channels...)
+}
+
+// JoinedChannels 返回已创建并加入的通道ID
+func (setup *FabricSetup) JoinedChannels() []string {
+	m := &setup.Util.channels
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ids []string
+	for id := range m.joined {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// JoinedChannel 返回已加入通道的配置
+func (setup *FabricSetup) JoinedChannel(channelID string) (ChannelConfig, bool) {
+	m := &setup.Util.channels
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	channelConfig, ok := m.joined[channelID]
+	return channelConfig, ok
+}
+
+// orgAdminIdentity 返回组织管理员的签名身份
+func (setup *FabricSetup) orgAdminIdentity(org Org) (msp.SigningIdentity, error) {
+	mspClient, err := mspclient.New(setup.Util.sdk.Context(), mspclient.WithOrg(org.Name))
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create MSP client of "+org.Name)
+	}
+	identity, err := mspClient.GetSigningIdentity(org.Admin)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get admin signing identity of "+org.Name)
+	}
+	return identity, nil
+}
+
+// ChannelExists 向排序节点查询通道配置, 判断通道是否已创建
+func (setup *FabricSetup) ChannelExists(channelID string) (bool, error) {
+	return setup.ChannelExistsContext(context.Background(), channelID)
+}
+
+// ChannelExistsContext 向排序节点查询通道是否存在
+func (setup *FabricSetup) ChannelExistsContext(ctx context.Context, channelID string) (bool, error) {
+	if err := setup.requireState(StateSDKCreated); err != nil {
+		return false, err
+	}
+
+	_, err := setup.Util.admin.QueryConfigFromOrderer(channelID, resmgmtOptions(ctx, resmgmt.WithOrdererEndpoint(setup.Org.OrderID))...)
+	if err == nil {
+		return true, nil
+	}
+	// The orderer answers NOT_FOUND for a channel it doesn't know, any other error means it can't tell
+	if isChannelNotFound(err) {
+		return false, nil
+	}
+	return false, errors.WithMessage(err, "failed to query channel config from orderer")
+}
+
+// ordererDeliverStatus 排序节点 Deliver 返回非成功状态时SDK给出的错误, 后接状态名
+const ordererDeliverStatus = "error status from ordering service "
+
+// isChannelNotFound 判断排序节点是否以 NOT_FOUND 状态拒绝了通道查询
+func isChannelNotFound(err error) bool {
+	if errs, ok := errors.Cause(err).(multi.Errors); ok {
+		for _, e := range errs {
+			if isChannelNotFound(e) {
+				return true
+			}
+		}
+		return false
+	}
+	if s, ok := status.FromError(err); ok {
+		return s.Group == status.OrdererServerStatus && s.Code == int32(cb.Status_NOT_FOUND)
+	}
+	// The deliver client doesn't attach a status to the error, only the status name
+	return errors.Cause(err).Error() == ordererDeliverStatus+cb.Status_NOT_FOUND.String()
+}
+
+// CreateChannel 使用 .tx 文件创建通道(排序节点上已存在时跳过), 只让尚未加入的peer加入,
+// 返回每个peer的加入结果
+func (setup *FabricSetup) CreateChannel(channelConfig ChannelConfig) ([]PeerResult, error) {
+	return setup.CreateChannelContext(context.Background(), channelConfig)
+}
+
+// CreateChannelContext 创建通道并让各peer加入, ctx 取消时已完成的步骤不会回滚
+func (setup *FabricSetup) CreateChannelContext(ctx context.Context, channelConfig ChannelConfig) ([]PeerResult, error) {
+	if err := setup.requireState(StateSDKCreated); err != nil {
+		return nil, err
+	}
+
+	exists, err := setup.ChannelExistsContext(ctx, channelConfig.ID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		setup.log().WithField(LogFieldChannel, channelConfig.ID).Info("Channel has created")
+	} else {
+		adminIdentity, err := setup.Util.msp.GetSigningIdentity(setup.Org.Admin)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to get admin signing identity")
+		}
+
+		req := resmgmt.SaveChannelRequest{ChannelID: channelConfig.ID, ChannelConfigPath: channelConfig.FilePath, SigningIdentities: []msp.SigningIdentity{adminIdentity}}
+		txID, err := setup.Util.admin.SaveChannel(req, resmgmtOptions(ctx, resmgmt.WithOrdererEndpoint(setup.Org.OrderID))...)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to save channel "+channelConfig.ID)
+		}
+		if txID.TransactionID == "" {
+			return nil, errors.New("failed to save channel " + channelConfig.ID + ": no transaction ID")
+		}
+		setup.log().WithFields(logrus.Fields{LogFieldChannel: channelConfig.ID, LogFieldTxID: txID.TransactionID}).Info("Channel created")
+	}
+
+	// Make each org's admin join its missing peers to the channel
+	var results []PeerResult
+	for _, org := range setup.AllOrgs() {
+		targets, err := setup.orgPeers(org, nil)
+		if err != nil {
+			return results, err
+		}
+		for _, peer := range targets {
+			results = append(results, setup.joinPeer(ctx, channelConfig.ID, org, peer))
+		}
+	}
+
+	m := &setup.Util.channels
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.joined == nil {
+		m.joined = make(map[string]ChannelConfig)
+	}
+	m.joined[channelConfig.ID] = channelConfig
+	return results, nil
+}
+
+// joinPeer 让单个peer加入通道, 已加入时跳过
+func (setup *FabricSetup) joinPeer(ctx context.Context, channelID string, org Org, peer fabApi.Peer) PeerResult {
+	log := setup.log().WithFields(logrus.Fields{LogFieldOrg: org.Name, LogFieldChannel: channelID, LogFieldPeer: peer.URL()})
+	result := PeerResult{Org: org.Name, Peer: peer.URL()}
+
+	joined, err := IsJoinedChannel(channelID, setup.Util.admins[org.Name], peer, resmgmtOptions(ctx)...)
+	if err != nil {
+		log.WithError(err).Warn("Failed to query joined channels")
+		result.Error = err.Error()
+		return result
+	}
+	if joined {
+		log.Info("Channel has joined")
+		result.Info = "already joined"
+		return result
+	}
+
+	err = setup.Util.admins[org.Name].JoinChannel(channelID, resmgmtOptions(ctx, resmgmt.WithTargets(peer), resmgmt.WithRetry(retry.DefaultResMgmtOpts), resmgmt.WithOrdererEndpoint(org.OrderID))...)
+	if err != nil {
+		log.WithError(err).Error("Failed to join channel")
+		result.Error = err.Error()
+		return result
+	}
+	result.Info = "joined"
+	log.Info("Channel joined")
+	return result
+}
+
+// QueryChannels 查询各组织每个peer已加入的通道
+func (setup *FabricSetup) QueryChannels() ([]PeerChannels, error) {
+	return setup.QueryChannelsContext(context.Background())
+}
+
+// QueryChannelsContext 逐个查询各peer加入的通道
+func (setup *FabricSetup) QueryChannelsContext(ctx context.Context) ([]PeerChannels, error) {
+	if err := setup.requireState(StateSDKCreated); err != nil {
+		return nil, err
+	}
+
+	var result []PeerChannels
+	for _, org := range setup.AllOrgs() {
+		orgPeers, err := setup.orgPeers(org, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, peer := range orgPeers {
+			resp, err := setup.Util.admins[org.Name].QueryChannels(resmgmtOptions(ctx, resmgmt.WithTargets(peer))...)
+			if err != nil {
+				return nil, errors.WithMessage(err, "failed to query channels of "+peer.URL())
+			}
+			item := PeerChannels{Org: org.Name, Peer: peer.URL(), Channels: []string{}}
+			for _, chInfo := range resp.Channels {
+				item.Channels = append(item.Channels, chInfo.ChannelId)
+			}
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// clientsFor 返回通道上的客户端, 不存在时创建
+func (setup *FabricSetup) clientsFor(channelID string) (*channelClients, error) {
+	if err := setup.requireState(StateChannelJoined); err != nil {
+		return nil, err
+	}
+
+	m := &setup.Util.channels
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if clients, ok := m.channels[channelID]; ok {
+		return clients, nil
+	}
+	if _, ok := m.joined[channelID]; !ok {
+		return nil, errors.Errorf("channel %s is not joined", channelID)
+	}
+
+	clientContext := setup.Util.sdk.ChannelContext(channelID, fabsdk.WithUser(setup.Org.User), fabsdk.WithOrg(setup.Org.Name))
+	channelClient, err := channel.New(clientContext)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create new channel client")
+	}
+	eventClient, err := event.New(clientContext)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create new event client")
+	}
+	ledgerClient, err := ledger.New(clientContext)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create new ledger client")
+	}
+
+	clients := &channelClients{channel: channelClient, event: eventClient, ledger: ledgerClient}
+	if m.channels == nil {
+		m.channels = make(map[string]*channelClients)
+	}
+	m.channels[channelID] = clients
+	setup.log().WithField(LogFieldChannel, channelID).Info("Channel clients created")
+	return clients, nil
+}
+
+// EventClient 返回通道上 Org.User 的事件客户端
+func (setup *FabricSetup) EventClient(channelID string) (*event.Client, error) {
+	clients, err := setup.clientsFor(channelID)
+	if err != nil {
+		return nil, err
+	}
+	return clients.event, nil
+}
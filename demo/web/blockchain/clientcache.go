@@ -0,0 +1,90 @@
+package blockchain
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+)
+
+const (
+	defaultClientCacheSize   = 100
+	defaultClientIdleTimeout = 30 * time.Minute
+)
+
+// ClientCacheConfig 按用户缓存通道客户端的配置, 零值使用默认值
+type ClientCacheConfig struct {
+	// Size 最多缓存的客户端数量, 超出时淘汰最久未使用的客户端
+	Size int
+	// IdleTimeout 客户端闲置超过该时间后被淘汰
+	IdleTimeout time.Duration
+}
+
+// clientCache 以 通道+组织+用户 为键的 LRU 通道客户端缓存
+type clientCache struct {
+	mu      sync.Mutex
+	config  ClientCacheConfig
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key      string
+	client   *channel.Client
+	lastUsed time.Time
+}
+
+// get 返回缓存的客户端, 不存在时调用 create 创建
+func (cache *clientCache) get(channelID, org, user string, create func() (*channel.Client, error)) (*channel.Client, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.entries == nil {
+		if cache.config.Size <= 0 {
+			cache.config.Size = defaultClientCacheSize
+		}
+		if cache.config.IdleTimeout <= 0 {
+			cache.config.IdleTimeout = defaultClientIdleTimeout
+		}
+		cache.order = list.New()
+		cache.entries = make(map[string]*list.Element)
+	}
+
+	now := time.Now()
+	cache.evictIdle(now)
+
+	key := channelID + "\x00" + org + "\x00" + user
+	if elem, ok := cache.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.lastUsed = now
+		cache.order.MoveToFront(elem)
+		return entry.client, nil
+	}
+
+	client, err := create()
+	if err != nil {
+		return nil, err
+	}
+	cache.entries[key] = cache.order.PushFront(&cacheEntry{key: key, client: client, lastUsed: now})
+
+	for cache.order.Len() > cache.config.Size {
+		cache.remove(cache.order.Back())
+	}
+	return client, nil
+}
+
+// evictIdle 从最久未使用的一端开始淘汰闲置客户端
+func (cache *clientCache) evictIdle(now time.Time) {
+	for elem := cache.order.Back(); elem != nil; elem = cache.order.Back() {
+		if now.Sub(elem.Value.(*cacheEntry).lastUsed) < cache.config.IdleTimeout {
+			return
+		}
+		cache.remove(elem)
+	}
+}
+
+func (cache *clientCache) remove(elem *list.Element) {
+	cache.order.Remove(elem)
+	delete(cache.entries, elem.Value.(*cacheEntry).key)
+}
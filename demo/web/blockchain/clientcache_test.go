@@ -0,0 +1,127 @@
+package blockchain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+	"github.com/pkg/errors"
+)
+
+// counter 统计 create 的调用次数, 每次返回新的客户端
+type counter struct {
+	created int
+}
+
+func (c *counter) create() (*channel.Client, error) {
+	c.created++
+	return &channel.Client{}, nil
+}
+
+func mustGet(t *testing.T, cache *clientCache, channelID, user string, create func() (*channel.Client, error)) *channel.Client {
+	return mustGetOrg(t, cache, channelID, "Org1", user, create)
+}
+
+func mustGetOrg(t *testing.T, cache *clientCache, channelID, org, user string, create func() (*channel.Client, error)) *channel.Client {
+	client, err := cache.get(channelID, org, user, create)
+	if err != nil {
+		t.Fatalf("get(%s, %s, %s): %v", channelID, org, user, err)
+	}
+	return client
+}
+
+func TestClientCacheReusesClient(t *testing.T) {
+	cache := &clientCache{}
+	c := &counter{}
+
+	first := mustGet(t, cache, "mychannel", "alice", c.create)
+	second := mustGet(t, cache, "mychannel", "alice", c.create)
+	if first != second {
+		t.Error("expected the cached client to be returned")
+	}
+	if c.created != 1 {
+		t.Errorf("created = %d, want 1", c.created)
+	}
+
+	// The same user on another channel gets its own client
+	mustGet(t, cache, "otherchannel", "alice", c.create)
+	if c.created != 2 {
+		t.Errorf("created = %d, want 2", c.created)
+	}
+
+	// A user of the same name in another org gets its own client
+	if got := mustGetOrg(t, cache, "mychannel", "Org2", "alice", c.create); got == first {
+		t.Error("Org2 alice should not get Org1 alice's client")
+	}
+	if c.created != 3 {
+		t.Errorf("created = %d, want 3", c.created)
+	}
+}
+
+func TestClientCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := &clientCache{config: ClientCacheConfig{Size: 2}}
+	c := &counter{}
+
+	alice := mustGet(t, cache, "mychannel", "alice", c.create)
+	mustGet(t, cache, "mychannel", "bob", c.create)
+	// Touch alice so bob becomes the least recently used
+	mustGet(t, cache, "mychannel", "alice", c.create)
+	mustGet(t, cache, "mychannel", "carol", c.create)
+
+	if len(cache.entries) != 2 {
+		t.Fatalf("entries = %d, want 2", len(cache.entries))
+	}
+	if got := mustGet(t, cache, "mychannel", "alice", c.create); got != alice {
+		t.Error("alice should still be cached")
+	}
+	if c.created != 3 {
+		t.Errorf("created = %d, want 3", c.created)
+	}
+
+	mustGet(t, cache, "mychannel", "bob", c.create)
+	if c.created != 4 {
+		t.Errorf("created = %d, want 4, bob should have been evicted", c.created)
+	}
+}
+
+func TestClientCacheEvictsIdleClients(t *testing.T) {
+	cache := &clientCache{config: ClientCacheConfig{IdleTimeout: time.Minute}}
+	c := &counter{}
+
+	alice := mustGet(t, cache, "mychannel", "alice", c.create)
+	mustGet(t, cache, "mychannel", "bob", c.create)
+
+	// alice has been idle for longer than the timeout, bob hasn't
+	cache.entries["mychannel\x00Org1\x00alice"].Value.(*cacheEntry).lastUsed = time.Now().Add(-2 * time.Minute)
+
+	if got := mustGet(t, cache, "mychannel", "bob", c.create); got == alice {
+		t.Error("bob should not get alice's client")
+	}
+	if _, ok := cache.entries["mychannel\x00Org1\x00alice"]; ok {
+		t.Error("idle client should have been evicted")
+	}
+	if got := mustGet(t, cache, "mychannel", "alice", c.create); got == alice {
+		t.Error("expected a new client for alice after eviction")
+	}
+	if c.created != 3 {
+		t.Errorf("created = %d, want 3", c.created)
+	}
+}
+
+func TestClientCacheDoesNotCacheErrors(t *testing.T) {
+	cache := &clientCache{}
+	failing := func() (*channel.Client, error) { return nil, errors.New("boom") }
+
+	if _, err := cache.get("mychannel", "Org1", "alice", failing); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(cache.entries) != 0 {
+		t.Errorf("entries = %d, want 0", len(cache.entries))
+	}
+
+	c := &counter{}
+	mustGet(t, cache, "mychannel", "alice", c.create)
+	if c.created != 1 {
+		t.Errorf("created = %d, want 1", c.created)
+	}
+}
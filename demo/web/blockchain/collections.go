@@ -0,0 +1,73 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	cb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+)
+
+// CollectionConfig 私有数据集合, 与 peer chaincode instantiate --collections-config 的JSON格式一致
+type CollectionConfig struct {
+	Name              string `json:"name"`
+	Policy            string `json:"policy"`
+	RequiredPeerCount int32  `json:"requiredPeerCount"`
+	MaxPeerCount      int32  `json:"maxPeerCount"`
+	BlockToLive       uint64 `json:"blockToLive"`
+}
+
+// LoadCollections 从JSON文件读取私有数据集合
+func LoadCollections(path string) ([]CollectionConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read collections config")
+	}
+
+	var collections []CollectionConfig
+	if err := json.Unmarshal(data, &collections); err != nil {
+		return nil, errors.Wrap(err, "failed to parse collections config")
+	}
+	return collections, nil
+}
+
+// collConfigFor 返回链码的私有数据集合配置, 未设置 CollectionsFile 时为空
+func (setup *FabricSetup) collConfigFor(chainCode ChainCode) ([]*cb.CollectionConfig, error) {
+	if chainCode.CollectionsFile == "" {
+		return nil, nil
+	}
+
+	collections, err := LoadCollections(chainCode.CollectionsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []*cb.CollectionConfig
+	for _, collection := range collections {
+		if collection.Name == "" {
+			return nil, errors.New("collection name is required")
+		}
+		if collection.MaxPeerCount < collection.RequiredPeerCount {
+			return nil, errors.Errorf("collection %s: maxPeerCount is less than requiredPeerCount", collection.Name)
+		}
+		policy, err := ParsePolicy(collection.Policy, setup.MspIDs())
+		if err != nil {
+			return nil, errors.WithMessage(err, "collection "+collection.Name)
+		}
+
+		configs = append(configs, &cb.CollectionConfig{
+			Payload: &cb.CollectionConfig_StaticCollectionConfig{
+				StaticCollectionConfig: &cb.StaticCollectionConfig{
+					Name: collection.Name,
+					MemberOrgsPolicy: &cb.CollectionPolicyConfig{
+						Payload: &cb.CollectionPolicyConfig_SignaturePolicy{SignaturePolicy: policy},
+					},
+					RequiredPeerCount: collection.RequiredPeerCount,
+					MaximumPeerCount:  collection.MaxPeerCount,
+					BlockToLive:       collection.BlockToLive,
+				},
+			},
+		})
+	}
+	return configs, nil
+}
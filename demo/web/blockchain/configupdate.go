@@ -0,0 +1,181 @@
+package blockchain
+
+import (
+	"bytes"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+)
+
+// ComputeConfigUpdate 计算从 original 到 updated 的通道配置更新, 与 configtxlator compute_update 的规则一致:
+// 只修改了成员内容的组版本不变, 增删了成员的组版本加一
+func ComputeConfigUpdate(channelID string, original, updated *cb.Config) (*cb.ConfigUpdate, error) {
+	if original.ChannelGroup == nil {
+		return nil, errors.New("no channel group included for original config")
+	}
+	if updated.ChannelGroup == nil {
+		return nil, errors.New("no channel group included for updated config")
+	}
+
+	readSet, writeSet, groupUpdated := computeGroupUpdate(original.ChannelGroup, updated.ChannelGroup)
+	if !groupUpdated {
+		return nil, errors.New("no differences detected between original and updated config")
+	}
+	return &cb.ConfigUpdate{ChannelId: channelID, ReadSet: readSet, WriteSet: writeSet}, nil
+}
+
+func newConfigGroup() *cb.ConfigGroup {
+	return &cb.ConfigGroup{
+		Groups:   make(map[string]*cb.ConfigGroup),
+		Values:   make(map[string]*cb.ConfigValue),
+		Policies: make(map[string]*cb.ConfigPolicy),
+	}
+}
+
+func computePoliciesMapUpdate(original, updated map[string]*cb.ConfigPolicy) (readSet, writeSet, sameSet map[string]*cb.ConfigPolicy, updatedMembers bool) {
+	readSet = make(map[string]*cb.ConfigPolicy)
+	writeSet = make(map[string]*cb.ConfigPolicy)
+	sameSet = make(map[string]*cb.ConfigPolicy)
+
+	for name, originalPolicy := range original {
+		updatedPolicy, ok := updated[name]
+		if !ok {
+			updatedMembers = true
+			continue
+		}
+		if originalPolicy.ModPolicy == updatedPolicy.ModPolicy && proto.Equal(originalPolicy.Policy, updatedPolicy.Policy) {
+			sameSet[name] = &cb.ConfigPolicy{Version: originalPolicy.Version}
+			continue
+		}
+		writeSet[name] = &cb.ConfigPolicy{Version: originalPolicy.Version + 1, ModPolicy: updatedPolicy.ModPolicy, Policy: updatedPolicy.Policy}
+	}
+
+	for name, updatedPolicy := range updated {
+		if _, ok := original[name]; ok {
+			continue
+		}
+		updatedMembers = true
+		writeSet[name] = &cb.ConfigPolicy{Version: 0, ModPolicy: updatedPolicy.ModPolicy, Policy: updatedPolicy.Policy}
+	}
+	return
+}
+
+func computeValuesMapUpdate(original, updated map[string]*cb.ConfigValue) (readSet, writeSet, sameSet map[string]*cb.ConfigValue, updatedMembers bool) {
+	readSet = make(map[string]*cb.ConfigValue)
+	writeSet = make(map[string]*cb.ConfigValue)
+	sameSet = make(map[string]*cb.ConfigValue)
+
+	for name, originalValue := range original {
+		updatedValue, ok := updated[name]
+		if !ok {
+			updatedMembers = true
+			continue
+		}
+		if originalValue.ModPolicy == updatedValue.ModPolicy && bytes.Equal(originalValue.Value, updatedValue.Value) {
+			sameSet[name] = &cb.ConfigValue{Version: originalValue.Version}
+			continue
+		}
+		writeSet[name] = &cb.ConfigValue{Version: originalValue.Version + 1, ModPolicy: updatedValue.ModPolicy, Value: updatedValue.Value}
+	}
+
+	for name, updatedValue := range updated {
+		if _, ok := original[name]; ok {
+			continue
+		}
+		updatedMembers = true
+		writeSet[name] = &cb.ConfigValue{Version: 0, ModPolicy: updatedValue.ModPolicy, Value: updatedValue.Value}
+	}
+	return
+}
+
+func computeGroupsMapUpdate(original, updated map[string]*cb.ConfigGroup) (readSet, writeSet, sameSet map[string]*cb.ConfigGroup, updatedMembers bool) {
+	readSet = make(map[string]*cb.ConfigGroup)
+	writeSet = make(map[string]*cb.ConfigGroup)
+	sameSet = make(map[string]*cb.ConfigGroup)
+
+	for name, originalGroup := range original {
+		updatedGroup, ok := updated[name]
+		if !ok {
+			updatedMembers = true
+			continue
+		}
+		groupReadSet, groupWriteSet, groupUpdated := computeGroupUpdate(originalGroup, updatedGroup)
+		if !groupUpdated {
+			sameSet[name] = groupReadSet
+			continue
+		}
+		readSet[name] = groupReadSet
+		writeSet[name] = groupWriteSet
+	}
+
+	for name, updatedGroup := range updated {
+		if _, ok := original[name]; ok {
+			continue
+		}
+		updatedMembers = true
+		_, groupWriteSet, _ := computeGroupUpdate(newConfigGroup(), updatedGroup)
+		writeSet[name] = &cb.ConfigGroup{
+			Version:   0,
+			ModPolicy: updatedGroup.ModPolicy,
+			Policies:  groupWriteSet.Policies,
+			Values:    groupWriteSet.Values,
+			Groups:    groupWriteSet.Groups,
+		}
+	}
+	return
+}
+
+func computeGroupUpdate(original, updated *cb.ConfigGroup) (readSet, writeSet *cb.ConfigGroup, updatedGroup bool) {
+	readSetPolicies, writeSetPolicies, sameSetPolicies, policiesMembersUpdated := computePoliciesMapUpdate(original.Policies, updated.Policies)
+	readSetValues, writeSetValues, sameSetValues, valuesMembersUpdated := computeValuesMapUpdate(original.Values, updated.Values)
+	readSetGroups, writeSetGroups, sameSetGroups, groupsMembersUpdated := computeGroupsMapUpdate(original.Groups, updated.Groups)
+
+	// If the members of the group are unchanged, the group version stays the same
+	if !(policiesMembersUpdated || valuesMembersUpdated || groupsMembersUpdated || original.ModPolicy != updated.ModPolicy) {
+		if len(readSetPolicies) == 0 && len(writeSetPolicies) == 0 &&
+			len(readSetValues) == 0 && len(writeSetValues) == 0 &&
+			len(readSetGroups) == 0 && len(writeSetGroups) == 0 {
+			return &cb.ConfigGroup{Version: original.Version}, &cb.ConfigGroup{Version: original.Version}, false
+		}
+
+		return &cb.ConfigGroup{
+			Version:  original.Version,
+			Policies: readSetPolicies,
+			Values:   readSetValues,
+			Groups:   readSetGroups,
+		}, &cb.ConfigGroup{
+			Version:  original.Version,
+			Policies: writeSetPolicies,
+			Values:   writeSetValues,
+			Groups:   writeSetGroups,
+		}, true
+	}
+
+	// Members were added or removed, every remaining member must be listed to bump the group version
+	for name, policy := range sameSetPolicies {
+		readSetPolicies[name] = policy
+		writeSetPolicies[name] = policy
+	}
+	for name, value := range sameSetValues {
+		readSetValues[name] = value
+		writeSetValues[name] = value
+	}
+	for name, group := range sameSetGroups {
+		readSetGroups[name] = group
+		writeSetGroups[name] = group
+	}
+
+	return &cb.ConfigGroup{
+		Version:  original.Version,
+		Policies: readSetPolicies,
+		Values:   readSetValues,
+		Groups:   readSetGroups,
+	}, &cb.ConfigGroup{
+		Version:   original.Version + 1,
+		Policies:  writeSetPolicies,
+		Values:    writeSetValues,
+		Groups:    writeSetGroups,
+		ModPolicy: updated.ModPolicy,
+	}, true
+}
@@ -0,0 +1,28 @@
+package blockchain
+
+import (
+	"context"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/ledger"
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/resmgmt"
+)
+
+// 所有 Fabric 调用都有对应的 XxxContext 版本, 作为父上下文传给 sdk: ctx 取消或到期时中止调用.
+// 每次调用仍使用 config.yaml 中对应类型的超时, ctx 的截止时间只会提前而不会延长它;
+// 不带 Context 的版本使用 context.Background()
+
+// channelOptions 将 ctx 转换为通道请求选项
+func channelOptions(ctx context.Context, options ...channel.RequestOption) []channel.RequestOption {
+	return append([]channel.RequestOption{channel.WithParentContext(ctx)}, options...)
+}
+
+// resmgmtOptions 将 ctx 转换为资源管理请求选项
+func resmgmtOptions(ctx context.Context, options ...resmgmt.RequestOption) []resmgmt.RequestOption {
+	return append([]resmgmt.RequestOption{resmgmt.WithParentContext(ctx)}, options...)
+}
+
+// ledgerOptions 将 ctx 转换为账本查询选项
+func ledgerOptions(ctx context.Context) []ledger.RequestOption {
+	return []ledger.RequestOption{ledger.WithParentContext(ctx)}
+}
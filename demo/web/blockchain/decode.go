@@ -0,0 +1,246 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/ledger/rwset"
+	"github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/ledger/rwset/kvrwset"
+	mspproto "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/msp"
+	pb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/peer"
+	"github.com/pkg/errors"
+)
+
+// Block 解析后的区块
+type Block struct {
+	Number       uint64         `json:"number"`
+	DataHash     string         `json:"data_hash"`
+	PreviousHash string         `json:"previous_hash"`
+	Transactions []*Transaction `json:"transactions"`
+}
+
+// Transaction 解析后的交易
+type Transaction struct {
+	TxID           string     `json:"tx_id"`
+	Type           string     `json:"type"`
+	ChannelID      string     `json:"channel_id"`
+	Timestamp      time.Time  `json:"timestamp"`
+	CreatorMSP     string     `json:"creator_msp"`
+	ChaincodeName  string     `json:"chaincode_name"`
+	Function       string     `json:"function"`
+	Args           []string   `json:"args"`
+	RWSets         []*NsRWSet `json:"rw_sets"`
+	Event          *CCEvent   `json:"event,omitempty"`
+	ValidationCode string     `json:"validation_code"`
+}
+
+// valid 判断交易是否通过了peer的校验, 只有有效交易的写集会进入状态数据库
+func (tx *Transaction) valid() bool {
+	return tx.ValidationCode == pb.TxValidationCode_VALID.String()
+}
+
+// CCEvent 交易中设置的链码事件
+type CCEvent struct {
+	ChaincodeID string `json:"chaincode_id"`
+	EventName   string `json:"event_name"`
+	Payload     string `json:"payload"`
+}
+
+// NsRWSet 单个链码命名空间的读写集
+type NsRWSet struct {
+	Namespace string    `json:"namespace"`
+	Reads     []KVRead  `json:"reads"`
+	Writes    []KVWrite `json:"writes"`
+}
+
+// KVRead 读集
+type KVRead struct {
+	Key      string `json:"key"`
+	BlockNum uint64 `json:"block_num"`
+	TxNum    uint64 `json:"tx_num"`
+}
+
+// KVWrite 写集
+type KVWrite struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	IsDelete bool   `json:"is_delete"`
+}
+
+// DecodeBlock 解析区块中的所有交易及其校验码
+func DecodeBlock(block *cb.Block) (*Block, error) {
+	if block == nil || block.Header == nil {
+		return nil, errors.New("block is empty")
+	}
+
+	result := &Block{
+		Number:       block.Header.Number,
+		DataHash:     hex.EncodeToString(block.Header.DataHash),
+		PreviousHash: hex.EncodeToString(block.Header.PreviousHash),
+	}
+
+	var txFilter []byte
+	if block.Metadata != nil && len(block.Metadata.Metadata) > int(cb.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+		txFilter = block.Metadata.Metadata[cb.BlockMetadataIndex_TRANSACTIONS_FILTER]
+	}
+
+	for i, data := range block.Data.Data {
+		env := &cb.Envelope{}
+		if err := proto.Unmarshal(data, env); err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal envelope %d of block %d", i, block.Header.Number)
+		}
+
+		tx, err := DecodeEnvelope(env)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to decode envelope")
+		}
+		if i < len(txFilter) {
+			tx.ValidationCode = pb.TxValidationCode(txFilter[i]).String()
+		}
+		result.Transactions = append(result.Transactions, tx)
+	}
+
+	return result, nil
+}
+
+// DecodeProcessedTransaction 解析已校验的交易
+func DecodeProcessedTransaction(processed *pb.ProcessedTransaction) (*Transaction, error) {
+	tx, err := DecodeEnvelope(processed.TransactionEnvelope)
+	if err != nil {
+		return nil, err
+	}
+	tx.ValidationCode = pb.TxValidationCode(processed.ValidationCode).String()
+	return tx, nil
+}
+
+// DecodeEnvelope 解析交易信封: 头部, 创建者MSP, 链码调用参数以及读写集
+func DecodeEnvelope(env *cb.Envelope) (*Transaction, error) {
+	if env == nil {
+		return nil, errors.New("envelope is empty")
+	}
+
+	payload := &cb.Payload{}
+	if err := proto.Unmarshal(env.Payload, payload); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal payload")
+	}
+	if payload.Header == nil {
+		return nil, errors.New("payload header is empty")
+	}
+
+	chdr := &cb.ChannelHeader{}
+	if err := proto.Unmarshal(payload.Header.ChannelHeader, chdr); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal channel header")
+	}
+	shdr := &cb.SignatureHeader{}
+	if err := proto.Unmarshal(payload.Header.SignatureHeader, shdr); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal signature header")
+	}
+	creator := &mspproto.SerializedIdentity{}
+	if err := proto.Unmarshal(shdr.Creator, creator); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal creator")
+	}
+
+	tx := &Transaction{
+		TxID:       chdr.TxId,
+		Type:       cb.HeaderType(chdr.Type).String(),
+		ChannelID:  chdr.ChannelId,
+		CreatorMSP: creator.Mspid,
+	}
+	if chdr.Timestamp != nil {
+		tx.Timestamp = time.Unix(chdr.Timestamp.Seconds, int64(chdr.Timestamp.Nanos))
+	}
+
+	// 只有背书交易才包含链码调用
+	if cb.HeaderType(chdr.Type) != cb.HeaderType_ENDORSER_TRANSACTION {
+		return tx, nil
+	}
+
+	transaction := &pb.Transaction{}
+	if err := proto.Unmarshal(payload.Data, transaction); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal transaction")
+	}
+
+	for _, action := range transaction.Actions {
+		if err := decodeTransactionAction(tx, action); err != nil {
+			return nil, err
+		}
+	}
+
+	return tx, nil
+}
+
+func decodeTransactionAction(tx *Transaction, action *pb.TransactionAction) error {
+	actionPayload := &pb.ChaincodeActionPayload{}
+	if err := proto.Unmarshal(action.Payload, actionPayload); err != nil {
+		return errors.Wrap(err, "failed to unmarshal chaincode action payload")
+	}
+
+	proposalPayload := &pb.ChaincodeProposalPayload{}
+	if err := proto.Unmarshal(actionPayload.ChaincodeProposalPayload, proposalPayload); err != nil {
+		return errors.Wrap(err, "failed to unmarshal chaincode proposal payload")
+	}
+	invocationSpec := &pb.ChaincodeInvocationSpec{}
+	if err := proto.Unmarshal(proposalPayload.Input, invocationSpec); err != nil {
+		return errors.Wrap(err, "failed to unmarshal chaincode invocation spec")
+	}
+	if spec := invocationSpec.ChaincodeSpec; spec != nil {
+		if spec.ChaincodeId != nil {
+			tx.ChaincodeName = spec.ChaincodeId.Name
+		}
+		if spec.Input != nil && len(spec.Input.Args) > 0 {
+			tx.Function = string(spec.Input.Args[0])
+			for _, arg := range spec.Input.Args[1:] {
+				tx.Args = append(tx.Args, string(arg))
+			}
+		}
+	}
+
+	if actionPayload.Action == nil {
+		return nil
+	}
+	responsePayload := &pb.ProposalResponsePayload{}
+	if err := proto.Unmarshal(actionPayload.Action.ProposalResponsePayload, responsePayload); err != nil {
+		return errors.Wrap(err, "failed to unmarshal proposal response payload")
+	}
+	chaincodeAction := &pb.ChaincodeAction{}
+	if err := proto.Unmarshal(responsePayload.Extension, chaincodeAction); err != nil {
+		return errors.Wrap(err, "failed to unmarshal chaincode action")
+	}
+	if len(chaincodeAction.Events) > 0 {
+		ccEvent := &pb.ChaincodeEvent{}
+		if err := proto.Unmarshal(chaincodeAction.Events, ccEvent); err != nil {
+			return errors.Wrap(err, "failed to unmarshal chaincode event")
+		}
+		tx.Event = &CCEvent{ChaincodeID: ccEvent.ChaincodeId, EventName: ccEvent.EventName, Payload: string(ccEvent.Payload)}
+	}
+
+	txRWSet := &rwset.TxReadWriteSet{}
+	if err := proto.Unmarshal(chaincodeAction.Results, txRWSet); err != nil {
+		return errors.Wrap(err, "failed to unmarshal read/write set")
+	}
+
+	for _, nsRWSet := range txRWSet.NsRwset {
+		kvRWSet := &kvrwset.KVRWSet{}
+		if err := proto.Unmarshal(nsRWSet.Rwset, kvRWSet); err != nil {
+			return errors.Wrapf(err, "failed to unmarshal kv read/write set of %s", nsRWSet.Namespace)
+		}
+
+		ns := &NsRWSet{Namespace: nsRWSet.Namespace}
+		for _, read := range kvRWSet.Reads {
+			kvRead := KVRead{Key: read.Key}
+			if read.Version != nil {
+				kvRead.BlockNum = read.Version.BlockNum
+				kvRead.TxNum = read.Version.TxNum
+			}
+			ns.Reads = append(ns.Reads, kvRead)
+		}
+		for _, write := range kvRWSet.Writes {
+			ns.Writes = append(ns.Writes, KVWrite{Key: write.Key, Value: string(write.Value), IsDelete: write.IsDelete})
+		}
+		tx.RWSets = append(tx.RWSets, ns)
+	}
+
+	return nil
+}
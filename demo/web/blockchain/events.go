@@ -0,0 +1,241 @@
+package blockchain
+
+import (
+	"sync"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/event"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	"github.com/hyperledger/fabric-sdk-go/pkg/fabsdk"
+	"github.com/pkg/errors"
+)
+
+// 事件类型
+const (
+	EventChaincode     = "chaincode"
+	EventBlock         = "block"
+	EventFilteredBlock = "filtered_block"
+)
+
+// subscriptionBuffer 每个订阅者的缓冲区大小, 缓冲区满时丢弃事件, 避免慢订阅者阻塞其他订阅者
+const subscriptionBuffer = 100
+
+// Event 推送给订阅者的事件
+type Event struct {
+	Type         string       `json:"type"`
+	BlockNumber  uint64       `json:"block_number"`
+	SourceURL    string       `json:"source_url"`
+	TxID         string       `json:"tx_id,omitempty"`
+	ChaincodeID  string       `json:"chaincode_id,omitempty"`
+	EventName    string       `json:"event_name,omitempty"`
+	Payload      string       `json:"payload,omitempty"`
+	Block        *Block       `json:"block,omitempty"`
+	Transactions []FilteredTx `json:"transactions,omitempty"`
+}
+
+// FilteredTx 过滤区块中的交易
+type FilteredTx struct {
+	TxID           string `json:"tx_id"`
+	Type           string `json:"type"`
+	ValidationCode string `json:"validation_code"`
+}
+
+// NewEventClient 以 Org.User 身份为默认通道创建事件客户端
+func (setup *FabricSetup) NewEventClient(opts ...event.ClientOption) (*event.Client, error) {
+	return setup.NewChannelEventClient(setup.ChannelConfig.ID, opts...)
+}
+
+// NewChannelEventClient 以 Org.User 身份为指定通道创建事件客户端
+func (setup *FabricSetup) NewChannelEventClient(channelID string, opts ...event.ClientOption) (*event.Client, error) {
+	if err := setup.requireState(StateChannelJoined); err != nil {
+		return nil, err
+	}
+
+	clientContext := setup.Util.sdk.ChannelContext(channelID, fabsdk.WithUser(setup.Org.User), fabsdk.WithOrg(setup.Org.Name))
+	client, err := event.New(clientContext, opts...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create new event client")
+	}
+	return client, nil
+}
+
+// EventHub 将同一类事件的一次注册分发给多个订阅者
+//
+// 第一个订阅者到来时向 event.Client 注册, 最后一个订阅者离开时取消注册
+type EventHub struct {
+	client *event.Client
+	mu     sync.Mutex
+	topics map[string]*topic
+}
+
+type topic struct {
+	registration fab.Registration
+	subscribers  map[*Subscription]struct{}
+}
+
+// Subscription 事件订阅, 通过 C 接收事件, 使用完毕后必须调用 Close
+type Subscription struct {
+	C   <-chan *Event
+	ch  chan *Event
+	hub *EventHub
+	key string
+}
+
+// NewEventHub 创建事件分发器
+func NewEventHub(setup *FabricSetup) (*EventHub, error) {
+	client, err := setup.NewEventClient(event.WithBlockEvents())
+	if err != nil {
+		return nil, err
+	}
+	return &EventHub{client: client, topics: make(map[string]*topic)}, nil
+}
+
+// SubscribeChaincode 订阅链码事件, filter 为事件名的正则表达式
+func (hub *EventHub) SubscribeChaincode(ccID, filter string) (*Subscription, error) {
+	key := EventChaincode + ":" + ccID + ":" + filter
+	return hub.subscribe(key, func() (fab.Registration, error) {
+		registration, events, err := hub.client.RegisterChaincodeEvent(ccID, filter)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to register chaincode event")
+		}
+		go func() {
+			for e := range events {
+				hub.broadcast(key, &Event{
+					Type:        EventChaincode,
+					BlockNumber: e.BlockNumber,
+					SourceURL:   e.SourceURL,
+					TxID:        e.TxID,
+					ChaincodeID: e.ChaincodeID,
+					EventName:   e.EventName,
+					Payload:     string(e.Payload),
+				})
+			}
+		}()
+		return registration, nil
+	})
+}
+
+// SubscribeBlocks 订阅完整区块事件
+func (hub *EventHub) SubscribeBlocks() (*Subscription, error) {
+	key := EventBlock
+	return hub.subscribe(key, func() (fab.Registration, error) {
+		registration, events, err := hub.client.RegisterBlockEvent()
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to register block event")
+		}
+		go func() {
+			for e := range events {
+				block, err := DecodeBlock(e.Block)
+				if err != nil {
+					continue
+				}
+				hub.broadcast(key, &Event{Type: EventBlock, BlockNumber: block.Number, SourceURL: e.SourceURL, Block: block})
+			}
+		}()
+		return registration, nil
+	})
+}
+
+// SubscribeFilteredBlocks 订阅过滤区块事件, 只包含交易ID与校验码
+func (hub *EventHub) SubscribeFilteredBlocks() (*Subscription, error) {
+	key := EventFilteredBlock
+	return hub.subscribe(key, func() (fab.Registration, error) {
+		registration, events, err := hub.client.RegisterFilteredBlockEvent()
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to register filtered block event")
+		}
+		go func() {
+			for e := range events {
+				if e.FilteredBlock == nil {
+					continue
+				}
+				item := &Event{Type: EventFilteredBlock, BlockNumber: e.FilteredBlock.Number, SourceURL: e.SourceURL}
+				for _, tx := range e.FilteredBlock.FilteredTransactions {
+					item.Transactions = append(item.Transactions, FilteredTx{TxID: tx.Txid, Type: tx.Type.String(), ValidationCode: tx.TxValidationCode.String()})
+				}
+				hub.broadcast(key, item)
+			}
+		}()
+		return registration, nil
+	})
+}
+
+// Close 关闭所有订阅
+func (hub *EventHub) Close() {
+	hub.mu.Lock()
+	topics := hub.topics
+	hub.topics = make(map[string]*topic)
+	for _, t := range topics {
+		for sub := range t.subscribers {
+			close(sub.ch)
+		}
+	}
+	hub.mu.Unlock()
+
+	for _, t := range topics {
+		hub.client.Unregister(t.registration)
+	}
+}
+
+// Close 取消订阅, 最后一个订阅者离开时取消事件注册
+func (sub *Subscription) Close() {
+	hub := sub.hub
+
+	hub.mu.Lock()
+	t, ok := hub.topics[sub.key]
+	if !ok {
+		hub.mu.Unlock()
+		return
+	}
+	if _, ok := t.subscribers[sub]; !ok {
+		hub.mu.Unlock()
+		return
+	}
+	delete(t.subscribers, sub)
+	close(sub.ch)
+	last := len(t.subscribers) == 0
+	if last {
+		delete(hub.topics, sub.key)
+	}
+	hub.mu.Unlock()
+
+	// 在锁外取消注册, 避免与正在分发事件的协程互相等待
+	if last {
+		hub.client.Unregister(t.registration)
+	}
+}
+
+func (hub *EventHub) subscribe(key string, register func() (fab.Registration, error)) (*Subscription, error) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	t, ok := hub.topics[key]
+	if !ok {
+		registration, err := register()
+		if err != nil {
+			return nil, err
+		}
+		t = &topic{registration: registration, subscribers: make(map[*Subscription]struct{})}
+		hub.topics[key] = t
+	}
+
+	ch := make(chan *Event, subscriptionBuffer)
+	sub := &Subscription{C: ch, ch: ch, hub: hub, key: key}
+	t.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+func (hub *EventHub) broadcast(key string, e *Event) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	t, ok := hub.topics[key]
+	if !ok {
+		return
+	}
+	for sub := range t.subscribers {
+		select {
+		case sub.ch <- e:
+		default:
+		}
+	}
+}
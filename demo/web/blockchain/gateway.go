@@ -0,0 +1,107 @@
+package blockchain
+
+import (
+	"context"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+	"github.com/hyperledger/fabric-sdk-go/pkg/fabsdk"
+	"github.com/pkg/errors"
+)
+
+// TxResult 链码调用结果
+type TxResult struct {
+	TxID           string   `json:"tx_id"`
+	Payload        string   `json:"payload"`
+	PayloadBase64  []byte   `json:"payload_base64"`
+	ValidationCode string   `json:"validation_code"`
+	Endorsers      []string `json:"endorsers"`
+}
+
+// NewTxResult 从 channel.Response 构建调用结果
+func NewTxResult(resp channel.Response) *TxResult {
+	result := &TxResult{
+		TxID:           string(resp.TransactionID),
+		Payload:        string(resp.Payload),
+		PayloadBase64:  resp.Payload,
+		ValidationCode: resp.TxValidationCode.String(),
+	}
+	for _, r := range resp.Responses {
+		result.Endorsers = append(result.Endorsers, r.Endorser)
+	}
+	return result
+}
+
+// ChannelClient 返回 org 组织的 user 在指定通道上的通道客户端, org 为空时使用 Org, user 为空时使用组织的 User
+//
+// 客户端按 通道+组织+用户 缓存, 闲置或超出缓存数量时被淘汰
+func (setup *FabricSetup) ChannelClient(channelID, org, user string) (*channel.Client, error) {
+	if err := setup.requireState(StateChannelJoined); err != nil {
+		return nil, err
+	}
+	if org == "" {
+		org = setup.Org.Name
+	}
+	orgs, err := setup.selectOrgs([]string{org})
+	if err != nil {
+		return nil, err
+	}
+	if user == "" {
+		user = orgs[0].User
+	}
+	if org == setup.Org.Name && user == setup.Org.User {
+		clients, err := setup.clientsFor(channelID)
+		if err != nil {
+			return nil, err
+		}
+		return clients.channel, nil
+	}
+
+	if _, ok := setup.JoinedChannel(channelID); !ok {
+		return nil, errors.Errorf("channel %s is not joined", channelID)
+	}
+	return setup.Util.clients.get(channelID, org, user, func() (*channel.Client, error) {
+		client, err := channel.New(setup.Util.sdk.ChannelContext(channelID, fabsdk.WithUser(user), fabsdk.WithOrg(org)))
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to create new channel client")
+		}
+		return client, nil
+	})
+}
+
+// ExecuteOnChannel 以 org 组织的 user 身份在指定通道上提交交易
+func (setup *FabricSetup) ExecuteOnChannel(channelID, org, user string, request channel.Request, options ...channel.RequestOption) (channel.Response, error) {
+	return setup.ExecuteOnChannelContext(context.Background(), channelID, org, user, request, options...)
+}
+
+// ExecuteOnChannelContext 以 org 组织的 user 身份在指定通道上提交交易
+func (setup *FabricSetup) ExecuteOnChannelContext(ctx context.Context, channelID, org, user string, request channel.Request, options ...channel.RequestOption) (channel.Response, error) {
+	client, err := setup.ChannelClient(channelID, org, user)
+	if err != nil {
+		return channel.Response{}, err
+	}
+
+	response, err := client.Execute(request, channelOptions(ctx, options...)...)
+	if err != nil {
+		return response, errors.WithMessage(err, "failed to execute chaincode")
+	}
+	return response, nil
+}
+
+// QueryOnChannel 以 org 组织的 user 身份在指定通道上查询链码
+func (setup *FabricSetup) QueryOnChannel(channelID, org, user string, request channel.Request, options ...channel.RequestOption) (channel.Response, error) {
+	return setup.QueryOnChannelContext(context.Background(), channelID, org, user, request, options...)
+}
+
+// QueryOnChannelContext 以 org 组织的 user 身份在指定通道上查询链码
+func (setup *FabricSetup) QueryOnChannelContext(ctx context.Context, channelID, org, user string, request channel.Request, options ...channel.RequestOption) (channel.Response, error) {
+	client, err := setup.ChannelClient(channelID, org, user)
+	if err != nil {
+		return channel.Response{}, err
+	}
+
+	response, err := client.Query(request, channelOptions(ctx, options...)...)
+	if err != nil {
+		return response, errors.WithMessage(err, "failed to query chaincode")
+	}
+	return response, nil
+}
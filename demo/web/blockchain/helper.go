@@ -0,0 +1,92 @@
+package blockchain
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/resmgmt"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/retry"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/status"
+	contextAPI "github.com/hyperledger/fabric-sdk-go/pkg/common/providers/context"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/core"
+	fabApi "github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	contextImpl "github.com/hyperledger/fabric-sdk-go/pkg/context"
+	"github.com/hyperledger/fabric-sdk-go/pkg/core/config/lookup"
+	"github.com/pkg/errors"
+)
+
+// OrgTargetPeers 返回配置文件中各组织的peer列表
+func OrgTargetPeers(orgs []string, configBackend ...core.ConfigBackend) ([]string, error) {
+	networkConfig := fabApi.NetworkConfig{}
+	err := lookup.New(configBackend...).UnmarshalKey("organizations", &networkConfig.Organizations)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get organizations from config ")
+	}
+
+	var peers []string
+	for _, org := range orgs {
+		orgConfig, ok := networkConfig.Organizations[strings.ToLower(org)]
+		if !ok {
+			continue
+		}
+		peers = append(peers, orgConfig.Peers...)
+	}
+	return peers, nil
+}
+
+// DiscoverLocalPeers queries the local peers for the given MSP context and returns all of the peers. If
+// the number of peers does not match the expected number then an error is returned.
+func DiscoverLocalPeers(ctxProvider contextAPI.ClientProvider, expectedPeers int) ([]fabApi.Peer, error) {
+	ctx, err := contextImpl.NewLocal(ctxProvider)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating local context")
+	}
+
+	discoveredPeers, err := retry.NewInvoker(retry.New(retry.TestRetryOpts)).Invoke(
+		func() (interface{}, error) {
+			peers, err := ctx.LocalDiscoveryService().GetPeers()
+			if err != nil {
+				return nil, errors.Wrapf(err, "error getting peers for MSP [%s]", ctx.Identifier().MSPID)
+			}
+			if len(peers) < expectedPeers {
+				return nil, status.New(status.TestStatus, status.GenericTransient.ToInt32(), fmt.Sprintf("Expecting %d peers but got %d", expectedPeers, len(peers)), nil)
+			}
+			return peers, nil
+		},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return discoveredPeers.([]fabApi.Peer), nil
+}
+
+// IsJoinedChannel 检查peer是否已加入通道
+func IsJoinedChannel(channelID string, resMgmtClient *resmgmt.Client, peer fabApi.Peer, options ...resmgmt.RequestOption) (bool, error) {
+	resp, err := resMgmtClient.QueryChannels(append([]resmgmt.RequestOption{resmgmt.WithTargets(peer)}, options...)...)
+	if err != nil {
+		return false, err
+	}
+	for _, chInfo := range resp.Channels {
+		if chInfo.ChannelId == channelID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isCCInstalled 检查链码的指定版本是否已安装在peer上
+func isCCInstalled(resMgmt *resmgmt.Client, ccName, ccVersion string, peer fabApi.Peer, options ...resmgmt.RequestOption) (bool, error) {
+	resp, err := resMgmt.QueryInstalledChaincodes(append([]resmgmt.RequestOption{resmgmt.WithTargets(peer)}, options...)...)
+	if err != nil {
+		return false, errors.WithMessage(err, "failed to query installed chaincodes of "+peer.URL())
+	}
+
+	for _, ccInfo := range resp.Chaincodes {
+		if ccInfo.Name == ccName && ccInfo.Version == ccVersion {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,169 @@
+package blockchain
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"sync"
+
+	mspclient "github.com/hyperledger/fabric-sdk-go/pkg/client/msp"
+	"github.com/pkg/errors"
+)
+
+// RegisterAdmin 在CA注册并登记一个可以继续注册和吊销用户的管理员账号
+func (setup *FabricSetup) RegisterAdmin(accountID, password string) error {
+	_, err := setup.Register(&mspclient.RegistrationRequest{
+		Name:   accountID,
+		Secret: password,
+		Type:   "client",
+		Attributes: []mspclient.Attribute{
+			{Name: "hf.Registrar.Roles", Value: "client,user"},
+			{Name: "hf.Revoker", Value: "true"},
+			{Name: "admin", Value: "true", ECert: true},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return setup.Enroll(accountID, password)
+}
+
+// Register 使用CA注册员身份注册用户, 返回登记密码
+func (setup *FabricSetup) Register(request *mspclient.RegistrationRequest) (string, error) {
+	if err := setup.requireState(StateSDKCreated); err != nil {
+		return "", err
+	}
+
+	secret, err := setup.Util.msp.Register(request)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to register identity")
+	}
+	return secret, nil
+}
+
+// Enroll 登记用户, 证书保存在 credentialStore 中
+func (setup *FabricSetup) Enroll(enrollmentID, secret string) error {
+	if err := setup.requireState(StateSDKCreated); err != nil {
+		return err
+	}
+
+	if err := setup.Util.msp.Enroll(enrollmentID, mspclient.WithSecret(secret)); err != nil {
+		return errors.WithMessage(err, "failed to enroll identity")
+	}
+	return nil
+}
+
+// Login 校验用户密码
+//
+// 用户已有签名身份且密码与上次登记时一致则直接通过, 否则向CA登记以校验密码, 避免每次登录都重新签发证书
+func (setup *FabricSetup) Login(enrollmentID, secret string) error {
+	if err := setup.requireState(StateSDKCreated); err != nil {
+		return err
+	}
+
+	if _, err := setup.Util.msp.GetSigningIdentity(enrollmentID); err == nil && setup.Util.secrets.verify(enrollmentID, secret) {
+		return nil
+	}
+	if err := setup.Enroll(enrollmentID, secret); err != nil {
+		return err
+	}
+	setup.Util.secrets.store(enrollmentID, secret)
+	return nil
+}
+
+// Reenroll 重新登记用户, 用于证书到期前更新证书
+func (setup *FabricSetup) Reenroll(enrollmentID string) error {
+	if err := setup.requireState(StateSDKCreated); err != nil {
+		return err
+	}
+
+	if err := setup.Util.msp.Reenroll(enrollmentID); err != nil {
+		return errors.WithMessage(err, "failed to reenroll identity")
+	}
+	return nil
+}
+
+// Revoke 吊销用户的所有证书
+func (setup *FabricSetup) Revoke(enrollmentID, reason string) (*mspclient.RevocationResponse, error) {
+	if err := setup.requireState(StateSDKCreated); err != nil {
+		return nil, err
+	}
+
+	resp, err := setup.Util.msp.Revoke(&mspclient.RevocationRequest{Name: enrollmentID, Reason: reason})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to revoke identity")
+	}
+	setup.Util.secrets.forget(enrollmentID)
+	return resp, nil
+}
+
+// Identities 返回CA中注册员可见的所有用户
+func (setup *FabricSetup) Identities() ([]*mspclient.IdentityResponse, error) {
+	if err := setup.requireState(StateSDKCreated); err != nil {
+		return nil, err
+	}
+
+	identities, err := setup.Util.msp.GetAllIdentities()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get identities")
+	}
+	return identities, nil
+}
+
+// Identity 查询CA中的用户
+func (setup *FabricSetup) Identity(enrollmentID string) (*mspclient.IdentityResponse, error) {
+	if err := setup.requireState(StateSDKCreated); err != nil {
+		return nil, err
+	}
+
+	identity, err := setup.Util.msp.GetIdentity(enrollmentID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get identity")
+	}
+	return identity, nil
+}
+
+// secretCache 保存登记成功的密码摘要(仅在内存中), 用于已登记用户再次登录时校验密码
+type secretCache struct {
+	mu      sync.Mutex
+	key     []byte
+	digests map[string][]byte
+}
+
+func (cache *secretCache) digest(enrollmentID, secret string) []byte {
+	if cache.key == nil {
+		cache.key = make([]byte, 32)
+		if _, err := rand.Read(cache.key); err != nil {
+			panic(err)
+		}
+	}
+	mac := hmac.New(sha256.New, cache.key)
+	mac.Write([]byte(enrollmentID + "\x00" + secret))
+	return mac.Sum(nil)
+}
+
+func (cache *secretCache) verify(enrollmentID, secret string) bool {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	expected, ok := cache.digests[enrollmentID]
+	return ok && hmac.Equal(expected, cache.digest(enrollmentID, secret))
+}
+
+func (cache *secretCache) store(enrollmentID, secret string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.digests == nil {
+		cache.digests = make(map[string][]byte)
+	}
+	cache.digests[enrollmentID] = cache.digest(enrollmentID, secret)
+}
+
+func (cache *secretCache) forget(enrollmentID string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	delete(cache.digests, enrollmentID)
+}
@@ -0,0 +1,26 @@
+package blockchain
+
+import "testing"
+
+func TestSecretCache(t *testing.T) {
+	cache := &secretCache{}
+	if cache.verify("alice", "secret") {
+		t.Fatal("unknown user should not be verified")
+	}
+
+	cache.store("alice", "secret")
+	if !cache.verify("alice", "secret") {
+		t.Error("stored secret should be verified")
+	}
+	if cache.verify("alice", "wrong") {
+		t.Error("wrong secret should not be verified")
+	}
+	if cache.verify("bob", "secret") {
+		t.Error("secret of alice should not verify bob")
+	}
+
+	cache.forget("alice")
+	if cache.verify("alice", "secret") {
+		t.Error("forgotten user should not be verified")
+	}
+}
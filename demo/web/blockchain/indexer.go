@@ -0,0 +1,381 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/event"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	cb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	bucketMeta         = []byte("meta")
+	bucketBlocks       = []byte("blocks")
+	bucketTransactions = []byte("transactions")
+	bucketKeyIndex     = []byte("index_key")
+	bucketCreatorIndex = []byte("index_creator")
+	bucketTimeIndex    = []byte("index_time")
+
+	keyNextBlock = []byte("next_block")
+)
+
+// positionLen 索引键末尾的交易位置长度: 区块号(8字节) + 区块内序号(4字节)
+const positionLen = 12
+
+// IndexedTransaction 本地索引中的交易
+type IndexedTransaction struct {
+	BlockNumber uint64 `json:"block_number"`
+	TxIndex     int    `json:"tx_index"`
+	*Transaction
+}
+
+// IndexedBlock 本地索引中的区块摘要
+type IndexedBlock struct {
+	Number       uint64   `json:"number"`
+	DataHash     string   `json:"data_hash"`
+	PreviousHash string   `json:"previous_hash"`
+	TxIDs        []string `json:"tx_ids"`
+}
+
+// Indexer 将默认通道(FabricSetup.ChannelConfig)的账本同步到本地 BoltDB, 以便按 key, 创建者和时间范围查询交易,
+// 其他通道不会被索引
+//
+// 启动时先订阅区块事件, 再通过 ledger.Client 从本地已同步高度补齐历史区块,
+// 重启后从上次保存的高度继续同步
+type Indexer struct {
+	setup        *FabricSetup
+	db           *bolt.DB
+	event        *event.Client
+	registration fab.Registration
+	mu           sync.Mutex
+	done         chan struct{}
+}
+
+// NewIndexer 打开(或创建)索引数据库
+func NewIndexer(setup *FabricSetup, path string) (*Indexer, error) {
+	if err := setup.requireState(StateChannelJoined); err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open index database")
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketMeta, bucketBlocks, bucketTransactions, bucketKeyIndex, bucketCreatorIndex, bucketTimeIndex} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "failed to create index buckets")
+	}
+
+	return &Indexer{setup: setup, db: db, done: make(chan struct{})}, nil
+}
+
+// Start 订阅区块事件并补齐本地缺失的区块
+func (idx *Indexer) Start() error {
+	eventClient, err := idx.setup.NewEventClient(event.WithBlockEvents())
+	if err != nil {
+		return err
+	}
+
+	registration, blocks, err := eventClient.RegisterBlockEvent()
+	if err != nil {
+		return errors.WithMessage(err, "failed to register block event")
+	}
+	idx.event = eventClient
+	idx.registration = registration
+
+	// 先注册事件再追赶, 追赶期间产生的区块会在事件中补上
+	if err := idx.catchUp(); err != nil {
+		eventClient.Unregister(registration)
+		return err
+	}
+
+	go idx.run(blocks)
+	return nil
+}
+
+// Close 取消事件订阅并关闭数据库
+func (idx *Indexer) Close() error {
+	if idx.event != nil {
+		idx.event.Unregister(idx.registration)
+		<-idx.done
+	}
+	return idx.db.Close()
+}
+
+// Height 返回本地已同步的区块高度
+func (idx *Indexer) Height() (uint64, error) {
+	var height uint64
+	err := idx.db.View(func(tx *bolt.Tx) error {
+		if value := tx.Bucket(bucketMeta).Get(keyNextBlock); value != nil {
+			height = binary.BigEndian.Uint64(value)
+		}
+		return nil
+	})
+	return height, err
+}
+
+// Block 查询本地区块摘要
+func (idx *Indexer) Block(number uint64) (*IndexedBlock, error) {
+	var block *IndexedBlock
+	err := idx.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(bucketBlocks).Get(uint64Bytes(number))
+		if value == nil {
+			return errors.Errorf("block %d not indexed", number)
+		}
+		block = &IndexedBlock{}
+		return json.Unmarshal(value, block)
+	})
+	return block, err
+}
+
+// Transaction 查询本地交易
+func (idx *Indexer) Transaction(txID string) (*IndexedTransaction, error) {
+	var result *IndexedTransaction
+	err := idx.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(bucketTransactions).Get([]byte(txID))
+		if value == nil {
+			return errors.Errorf("transaction %s not indexed", txID)
+		}
+		result = &IndexedTransaction{}
+		return json.Unmarshal(value, result)
+	})
+	return result, err
+}
+
+// TransactionsByKey 查询写入过链码 namespace 中 key 的有效交易, limit 为0时不限制数量
+func (idx *Indexer) TransactionsByKey(namespace, key string, limit int) ([]*IndexedTransaction, error) {
+	return idx.scanPrefix(bucketKeyIndex, compositeKey(namespace, key), limit, true)
+}
+
+// TransactionsByCreator 查询由 mspID 提交的交易, 包括校验失败的交易
+func (idx *Indexer) TransactionsByCreator(mspID string, limit int) ([]*IndexedTransaction, error) {
+	return idx.scanPrefix(bucketCreatorIndex, compositeKey(mspID), limit, false)
+}
+
+// TransactionsByTime 查询时间范围 [from, to) 内的交易
+func (idx *Indexer) TransactionsByTime(from, to time.Time, limit int) ([]*IndexedTransaction, error) {
+	var result []*IndexedTransaction
+	err := idx.db.View(func(tx *bolt.Tx) error {
+		txs := tx.Bucket(bucketTransactions)
+		min := uint64Bytes(uint64(from.UnixNano()))
+		max := uint64Bytes(uint64(to.UnixNano()))
+
+		cursor := tx.Bucket(bucketTimeIndex).Cursor()
+		for k, v := cursor.Seek(min); k != nil && bytes.Compare(k[:8], max) < 0; k, v = cursor.Next() {
+			if limit > 0 && len(result) >= limit {
+				break
+			}
+			item, err := loadTransaction(txs, v)
+			if err != nil {
+				return err
+			}
+			result = append(result, item)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// scanPrefix 按索引前缀查询交易, validOnly 时跳过校验失败的交易
+func (idx *Indexer) scanPrefix(bucket, prefix []byte, limit int, validOnly bool) ([]*IndexedTransaction, error) {
+	var result []*IndexedTransaction
+	err := idx.db.View(func(tx *bolt.Tx) error {
+		txs := tx.Bucket(bucketTransactions)
+
+		cursor := tx.Bucket(bucket).Cursor()
+		for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+			// key 中可能包含分隔符, 只接受前缀后紧跟交易位置的记录
+			if len(k)-len(prefix) != positionLen {
+				continue
+			}
+			if limit > 0 && len(result) >= limit {
+				break
+			}
+			item, err := loadTransaction(txs, v)
+			if err != nil {
+				return err
+			}
+			if validOnly && !item.valid() {
+				continue
+			}
+			result = append(result, item)
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (idx *Indexer) run(blocks <-chan *fab.BlockEvent) {
+	defer close(idx.done)
+
+	for e := range blocks {
+		if err := idx.handleBlock(e.Block); err != nil {
+			idx.setup.log().WithError(err).WithField(LogFieldChannel, idx.setup.ChannelConfig.ID).Error("Failed to index block")
+		}
+	}
+}
+
+// handleBlock 保存事件中的区块, 与本地高度间存在缺口时先从账本补齐
+func (idx *Indexer) handleBlock(block *cb.Block) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	next, err := idx.Height()
+	if err != nil {
+		return err
+	}
+	number := block.Header.Number
+	if number < next {
+		return nil
+	}
+
+	for n := next; n < number; n++ {
+		missing, err := idx.setup.Util.ledger.QueryBlock(n)
+		if err != nil {
+			return errors.WithMessage(err, "failed to query missing block")
+		}
+		if err := idx.store(missing); err != nil {
+			return err
+		}
+	}
+	return idx.store(block)
+}
+
+// catchUp 从本地高度同步到账本当前高度
+func (idx *Indexer) catchUp() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	info, err := idx.setup.Util.ledger.QueryInfo()
+	if err != nil {
+		return errors.WithMessage(err, "failed to query blockchain info")
+	}
+	next, err := idx.Height()
+	if err != nil {
+		return err
+	}
+
+	for n := next; n < info.BCI.Height; n++ {
+		block, err := idx.setup.Util.ledger.QueryBlock(n)
+		if err != nil {
+			return errors.WithMessage(err, "failed to query block")
+		}
+		if err := idx.store(block); err != nil {
+			return err
+		}
+	}
+	idx.setup.log().WithFields(logrus.Fields{LogFieldChannel: idx.setup.ChannelConfig.ID, "height": info.BCI.Height}).Info("Indexer synced")
+	return nil
+}
+
+// store 在一个事务中保存区块, 交易及索引, 并推进本地高度
+func (idx *Indexer) store(block *cb.Block) error {
+	decoded, err := DecodeBlock(block)
+	if err != nil {
+		return err
+	}
+
+	return idx.db.Update(func(tx *bolt.Tx) error {
+		txs := tx.Bucket(bucketTransactions)
+		keyIndex := tx.Bucket(bucketKeyIndex)
+		creatorIndex := tx.Bucket(bucketCreatorIndex)
+		timeIndex := tx.Bucket(bucketTimeIndex)
+
+		summary := &IndexedBlock{Number: decoded.Number, DataHash: decoded.DataHash, PreviousHash: decoded.PreviousHash}
+		for i, item := range decoded.Transactions {
+			if item.TxID == "" {
+				continue
+			}
+			summary.TxIDs = append(summary.TxIDs, item.TxID)
+
+			value, err := json.Marshal(&IndexedTransaction{BlockNumber: decoded.Number, TxIndex: i, Transaction: item})
+			if err != nil {
+				return err
+			}
+			txID := []byte(item.TxID)
+			if err := txs.Put(txID, value); err != nil {
+				return err
+			}
+
+			position := txPosition(decoded.Number, i)
+			if err := creatorIndex.Put(append(compositeKey(item.CreatorMSP), position...), txID); err != nil {
+				return err
+			}
+			if err := timeIndex.Put(append(uint64Bytes(uint64(item.Timestamp.UnixNano())), position...), txID); err != nil {
+				return err
+			}
+
+			// Writes of an invalidated transaction (MVCC conflict, endorsement failure) never reached the state
+			if !item.valid() {
+				continue
+			}
+			for _, ns := range item.RWSets {
+				for _, write := range ns.Writes {
+					if err := keyIndex.Put(append(compositeKey(ns.Namespace, write.Key), position...), txID); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		value, err := json.Marshal(summary)
+		if err != nil {
+			return err
+		}
+		if err := tx.Bucket(bucketBlocks).Put(uint64Bytes(decoded.Number), value); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketMeta).Put(keyNextBlock, uint64Bytes(decoded.Number+1))
+	})
+}
+
+func loadTransaction(txs *bolt.Bucket, txID []byte) (*IndexedTransaction, error) {
+	value := txs.Get(txID)
+	if value == nil {
+		return nil, errors.Errorf("transaction %s not indexed", txID)
+	}
+	item := &IndexedTransaction{}
+	if err := json.Unmarshal(value, item); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal indexed transaction")
+	}
+	return item, nil
+}
+
+// compositeKey 以 0x00 连接各部分, 末尾同样以 0x00 结束
+func compositeKey(parts ...string) []byte {
+	var buf bytes.Buffer
+	for _, part := range parts {
+		buf.WriteString(part)
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+func txPosition(blockNumber uint64, txIndex int) []byte {
+	position := make([]byte, positionLen)
+	binary.BigEndian.PutUint64(position, blockNumber)
+	binary.BigEndian.PutUint32(position[8:], uint32(txIndex))
+	return position
+}
+
+func uint64Bytes(n uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, n)
+	return b
+}
@@ -0,0 +1,47 @@
+package blockchain
+
+import (
+	"context"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+)
+
+// Execute 通过默认通道客户端提交交易
+func (setup *FabricSetup) Execute(request channel.Request, options ...channel.RequestOption) (channel.Response, error) {
+	return setup.ExecuteContext(context.Background(), request, options...)
+}
+
+// ExecuteContext 通过默认通道客户端提交交易
+func (setup *FabricSetup) ExecuteContext(ctx context.Context, request channel.Request, options ...channel.RequestOption) (channel.Response, error) {
+	return setup.ExecuteOnChannelContext(ctx, setup.ChannelConfig.ID, "", "", request, options...)
+}
+
+// Query 通过默认通道客户端查询链码, 不会提交到账本
+func (setup *FabricSetup) Query(request channel.Request, options ...channel.RequestOption) (channel.Response, error) {
+	return setup.QueryContext(context.Background(), request, options...)
+}
+
+// QueryContext 通过默认通道客户端查询链码
+func (setup *FabricSetup) QueryContext(ctx context.Context, request channel.Request, options ...channel.RequestOption) (channel.Response, error) {
+	return setup.QueryOnChannelContext(ctx, setup.ChannelConfig.ID, "", "", request, options...)
+}
+
+// ExecuteAs 以 org 组织的 user 身份在默认通道上提交交易
+func (setup *FabricSetup) ExecuteAs(org, user string, request channel.Request, options ...channel.RequestOption) (channel.Response, error) {
+	return setup.ExecuteAsContext(context.Background(), org, user, request, options...)
+}
+
+// ExecuteAsContext 以 org 组织的 user 身份在默认通道上提交交易
+func (setup *FabricSetup) ExecuteAsContext(ctx context.Context, org, user string, request channel.Request, options ...channel.RequestOption) (channel.Response, error) {
+	return setup.ExecuteOnChannelContext(ctx, setup.ChannelConfig.ID, org, user, request, options...)
+}
+
+// QueryAs 以 org 组织的 user 身份在默认通道上查询链码
+func (setup *FabricSetup) QueryAs(org, user string, request channel.Request, options ...channel.RequestOption) (channel.Response, error) {
+	return setup.QueryAsContext(context.Background(), org, user, request, options...)
+}
+
+// QueryAsContext 以 org 组织的 user 身份在默认通道上查询链码
+func (setup *FabricSetup) QueryAsContext(ctx context.Context, org, user string, request channel.Request, options ...channel.RequestOption) (channel.Response, error) {
+	return setup.QueryOnChannelContext(ctx, setup.ChannelConfig.ID, org, user, request, options...)
+}
@@ -0,0 +1,124 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/hex"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/ledger"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	"github.com/pkg/errors"
+)
+
+// BlockchainInfo 账本高度及区块哈希
+type BlockchainInfo struct {
+	Height            uint64 `json:"height"`
+	CurrentBlockHash  string `json:"current_block_hash"`
+	PreviousBlockHash string `json:"previous_block_hash"`
+	Endorser          string `json:"endorser"`
+}
+
+// ChannelLedger 单个通道的账本查询
+type ChannelLedger struct {
+	ChannelID string
+	client    *ledger.Client
+	ctx       context.Context
+}
+
+// Ledger 返回指定通道的账本查询
+func (setup *FabricSetup) Ledger(channelID string) (*ChannelLedger, error) {
+	clients, err := setup.clientsFor(channelID)
+	if err != nil {
+		return nil, err
+	}
+	return &ChannelLedger{ChannelID: channelID, client: clients.ledger, ctx: context.Background()}, nil
+}
+
+// WithContext 返回使用 ctx 查询的账本, ctx 取消时中止查询
+func (l *ChannelLedger) WithContext(ctx context.Context) *ChannelLedger {
+	copied := *l
+	copied.ctx = ctx
+	return &copied
+}
+
+// QueryInfo 查询默认通道的账本信息
+func (setup *FabricSetup) QueryInfo() (*BlockchainInfo, error) {
+	return setup.QueryInfoContext(context.Background())
+}
+
+// QueryInfoContext 查询默认通道的账本信息
+func (setup *FabricSetup) QueryInfoContext(ctx context.Context) (*BlockchainInfo, error) {
+	l, err := setup.Ledger(setup.ChannelConfig.ID)
+	if err != nil {
+		return nil, err
+	}
+	return l.WithContext(ctx).QueryInfo()
+}
+
+// QueryBlock 按区块号查询默认通道的区块
+func (setup *FabricSetup) QueryBlock(number uint64) (*Block, error) {
+	return setup.QueryBlockContext(context.Background(), number)
+}
+
+// QueryBlockContext 按区块号查询默认通道的区块
+func (setup *FabricSetup) QueryBlockContext(ctx context.Context, number uint64) (*Block, error) {
+	l, err := setup.Ledger(setup.ChannelConfig.ID)
+	if err != nil {
+		return nil, err
+	}
+	return l.WithContext(ctx).QueryBlock(number)
+}
+
+// QueryInfo 查询账本信息
+func (l *ChannelLedger) QueryInfo() (*BlockchainInfo, error) {
+	resp, err := l.client.QueryInfo(ledgerOptions(l.ctx)...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to query blockchain info")
+	}
+
+	return &BlockchainInfo{
+		Height:            resp.BCI.Height,
+		CurrentBlockHash:  hex.EncodeToString(resp.BCI.CurrentBlockHash),
+		PreviousBlockHash: hex.EncodeToString(resp.BCI.PreviousBlockHash),
+		Endorser:          resp.Endorser,
+	}, nil
+}
+
+// QueryBlock 按区块号查询区块
+func (l *ChannelLedger) QueryBlock(number uint64) (*Block, error) {
+	block, err := l.client.QueryBlock(number, ledgerOptions(l.ctx)...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to query block")
+	}
+	return DecodeBlock(block)
+}
+
+// QueryBlockByHash 按区块哈希(十六进制)查询区块
+func (l *ChannelLedger) QueryBlockByHash(hash string) (*Block, error) {
+	blockHash, err := hex.DecodeString(hash)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid block hash")
+	}
+	block, err := l.client.QueryBlockByHash(blockHash, ledgerOptions(l.ctx)...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to query block by hash")
+	}
+	return DecodeBlock(block)
+}
+
+// QueryBlockByTxID 查询交易所在的区块
+func (l *ChannelLedger) QueryBlockByTxID(txID string) (*Block, error) {
+	block, err := l.client.QueryBlockByTxID(fab.TransactionID(txID), ledgerOptions(l.ctx)...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to query block by transaction id")
+	}
+	return DecodeBlock(block)
+}
+
+// QueryTransaction 按交易ID查询交易
+func (l *ChannelLedger) QueryTransaction(txID string) (*Transaction, error) {
+	processed, err := l.client.QueryTransaction(fab.TransactionID(txID), ledgerOptions(l.ctx)...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to query transaction")
+	}
+	return DecodeProcessedTransaction(processed)
+}
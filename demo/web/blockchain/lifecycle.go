@@ -0,0 +1,304 @@
+package blockchain
+
+import (
+	"context"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/resmgmt"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/retry"
+	fabApi "github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	packager "github.com/hyperledger/fabric-sdk-go/pkg/fab/ccpackager/gopackager"
+	"github.com/hyperledger/fabric-sdk-go/pkg/fabsdk"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// PeerResult 单个peer上的操作结果
+type PeerResult struct {
+	Org    string `json:"org"`
+	Peer   string `json:"peer"`
+	Status int32  `json:"status"`
+	Info   string `json:"info,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ChainCodeInfo 已安装或已实例化的链码
+type ChainCodeInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Path    string `json:"path"`
+}
+
+// PeerChainCodes peer 上已安装的链码
+type PeerChainCodes struct {
+	Org        string          `json:"org"`
+	Peer       string          `json:"peer"`
+	ChainCodes []ChainCodeInfo `json:"chaincodes"`
+}
+
+// orgPeers 返回组织的peer, peers 不为空时只返回URL在其中的peer
+func (setup *FabricSetup) orgPeers(org Org, peers []string) ([]fabApi.Peer, error) {
+	provider := setup.Util.sdk.Context(fabsdk.WithUser(org.Admin), fabsdk.WithOrg(org.Name))
+	discovered, err := DiscoverLocalPeers(provider, 1)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to discover peers of "+org.Name)
+	}
+	if len(peers) == 0 {
+		return discovered, nil
+	}
+
+	wanted := make(map[string]bool)
+	for _, peer := range peers {
+		wanted[peer] = true
+	}
+	var targets []fabApi.Peer
+	for _, peer := range discovered {
+		if wanted[peer.URL()] {
+			targets = append(targets, peer)
+		}
+	}
+	return targets, nil
+}
+
+// selectOrgs 按组织名筛选参与组织, names 为空时返回全部
+func (setup *FabricSetup) selectOrgs(names []string) ([]Org, error) {
+	if len(names) == 0 {
+		return setup.AllOrgs(), nil
+	}
+
+	var orgs []Org
+	for _, name := range names {
+		found := false
+		for _, org := range setup.AllOrgs() {
+			if org.Name == name {
+				orgs = append(orgs, org)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.Errorf("unknown org %s", name)
+		}
+	}
+	return orgs, nil
+}
+
+// InstallChainCode 在指定组织(为空时全部组织)的peer上安装链码, peers 为空时安装到组织的所有peer
+//
+// 已安装相同名称与版本的peer返回 Info "already installed"
+func (setup *FabricSetup) InstallChainCode(chainCode ChainCode, orgNames []string, peers []string) ([]PeerResult, error) {
+	return setup.InstallChainCodeContext(context.Background(), chainCode, orgNames, peers)
+}
+
+// InstallChainCodeContext 在选定的peer上安装链码
+func (setup *FabricSetup) InstallChainCodeContext(ctx context.Context, chainCode ChainCode, orgNames []string, peers []string) ([]PeerResult, error) {
+	if err := setup.requireState(StateChannelJoined); err != nil {
+		return nil, err
+	}
+	orgs, err := setup.selectOrgs(orgNames)
+	if err != nil {
+		return nil, err
+	}
+
+	ccPkg, err := packager.NewCCPackage(chainCode.SrcPath, chainCode.GoPath)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create ChainCode package")
+	}
+	ccPkg.Code, err = repackage(ccPkg.Code, chainCode)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []PeerResult
+	for _, org := range orgs {
+		targets, err := setup.orgPeers(org, peers)
+		if err != nil {
+			return results, err
+		}
+		for _, peer := range targets {
+			log := setup.log().WithFields(logrus.Fields{LogFieldOrg: org.Name, LogFieldPeer: peer.URL(), LogFieldChaincode: chainCode.ID})
+			result := PeerResult{Org: org.Name, Peer: peer.URL()}
+
+			installed, err := isCCInstalled(setup.Util.admins[org.Name], chainCode.ID, chainCode.Version, peer, resmgmtOptions(ctx)...)
+			if err != nil {
+				log.WithError(err).Warn("Failed to query installed chaincodes")
+				result.Error = err.Error()
+				results = append(results, result)
+				continue
+			}
+			if installed {
+				log.Debug("ChainCode has installed")
+				result.Info = "already installed"
+				results = append(results, result)
+				continue
+			}
+
+			installCCReq := resmgmt.InstallCCRequest{Name: chainCode.ID, Path: chainCode.SrcPath, Version: chainCode.Version, Package: ccPkg}
+			resp, err := setup.Util.admins[org.Name].InstallCC(installCCReq, resmgmtOptions(ctx, resmgmt.WithTargets(peer), resmgmt.WithRetry(retry.DefaultResMgmtOpts))...)
+			for _, r := range resp {
+				result.Status = r.Status
+				result.Info = r.Info
+			}
+			if err != nil {
+				log.WithError(err).Error("Failed to install chaincode")
+				result.Error = err.Error()
+			} else {
+				log.WithField("version", chainCode.Version).Info("ChainCode installed")
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// InstantiateChainCode 在通道上实例化链码, args 为初始化参数
+func (setup *FabricSetup) InstantiateChainCode(channelID string, chainCode ChainCode, args [][]byte) (string, error) {
+	return setup.InstantiateChainCodeContext(context.Background(), channelID, chainCode, args)
+}
+
+// InstantiateChainCodeContext 在通道上实例化链码
+func (setup *FabricSetup) InstantiateChainCodeContext(ctx context.Context, channelID string, chainCode ChainCode, args [][]byte) (string, error) {
+	if err := setup.requireState(StateChannelJoined); err != nil {
+		return "", err
+	}
+
+	version, err := setup.instantiatedVersion(ctx, channelID, chainCode.ID)
+	if err != nil {
+		return "", err
+	}
+	if version != "" {
+		return "", errors.Errorf("chaincode %s is already instantiated on %s with version %s", chainCode.ID, channelID, version)
+	}
+
+	ccPolicy, err := setup.policyFor(chainCode)
+	if err != nil {
+		return "", err
+	}
+
+	collConfig, err := setup.collConfigFor(chainCode)
+	if err != nil {
+		return "", err
+	}
+
+	req := resmgmt.InstantiateCCRequest{Name: chainCode.ID, Path: chainCode.SrcPath, Version: chainCode.Version, Args: args, Policy: ccPolicy, CollConfig: collConfig}
+	resp, err := setup.Util.admin.InstantiateCC(channelID, req, resmgmtOptions(ctx, resmgmt.WithRetry(retry.DefaultResMgmtOpts))...)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to instantiate the chaincode")
+	}
+	if resp.TransactionID == "" {
+		return "", errors.New("failed to instantiate the chaincode: no transaction ID")
+	}
+	setup.log().WithFields(logrus.Fields{LogFieldChannel: channelID, LogFieldChaincode: chainCode.ID, LogFieldTxID: resp.TransactionID, "version": chainCode.Version}).Info("ChainCode instantiated")
+	return string(resp.TransactionID), nil
+}
+
+// UpgradeChainCode 将通道上的链码升级到新版本, 新版本需先安装, 版本号必须与当前实例化的版本不同
+func (setup *FabricSetup) UpgradeChainCode(channelID string, chainCode ChainCode, args [][]byte) (string, error) {
+	return setup.UpgradeChainCodeContext(context.Background(), channelID, chainCode, args)
+}
+
+// UpgradeChainCodeContext 将通道上的链码升级到 chainCode.Version
+func (setup *FabricSetup) UpgradeChainCodeContext(ctx context.Context, channelID string, chainCode ChainCode, args [][]byte) (string, error) {
+	if err := setup.requireState(StateChannelJoined); err != nil {
+		return "", err
+	}
+
+	version, err := setup.instantiatedVersion(ctx, channelID, chainCode.ID)
+	if err != nil {
+		return "", err
+	}
+	if version == "" {
+		return "", errors.Errorf("chaincode %s is not instantiated on %s", chainCode.ID, channelID)
+	}
+	if version == chainCode.Version {
+		return "", errors.Errorf("chaincode %s is already at version %s", chainCode.ID, version)
+	}
+
+	ccPolicy, err := setup.policyFor(chainCode)
+	if err != nil {
+		return "", err
+	}
+
+	collConfig, err := setup.collConfigFor(chainCode)
+	if err != nil {
+		return "", err
+	}
+
+	req := resmgmt.UpgradeCCRequest{Name: chainCode.ID, Path: chainCode.SrcPath, Version: chainCode.Version, Args: args, Policy: ccPolicy, CollConfig: collConfig}
+	resp, err := setup.Util.admin.UpgradeCC(channelID, req, resmgmtOptions(ctx, resmgmt.WithRetry(retry.DefaultResMgmtOpts))...)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to upgrade chaincode")
+	}
+	if resp.TransactionID == "" {
+		return "", errors.New("failed to upgrade chaincode: no transaction ID")
+	}
+	setup.log().WithFields(logrus.Fields{LogFieldChannel: channelID, LogFieldChaincode: chainCode.ID, LogFieldTxID: resp.TransactionID, "from": version, "version": chainCode.Version}).Info("ChainCode upgraded")
+	return string(resp.TransactionID), nil
+}
+
+// InstalledChainCodes 查询各组织每个peer上已安装的链码
+func (setup *FabricSetup) InstalledChainCodes() ([]PeerChainCodes, error) {
+	return setup.InstalledChainCodesContext(context.Background())
+}
+
+// InstalledChainCodesContext 逐个查询各peer已安装的链码
+func (setup *FabricSetup) InstalledChainCodesContext(ctx context.Context) ([]PeerChainCodes, error) {
+	if err := setup.requireState(StateSDKCreated); err != nil {
+		return nil, err
+	}
+
+	var result []PeerChainCodes
+	for _, org := range setup.AllOrgs() {
+		targets, err := setup.orgPeers(org, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, peer := range targets {
+			resp, err := setup.Util.admins[org.Name].QueryInstalledChaincodes(resmgmtOptions(ctx, resmgmt.WithTargets(peer))...)
+			if err != nil {
+				return nil, errors.WithMessage(err, "failed to query installed chaincodes of "+peer.URL())
+			}
+			item := PeerChainCodes{Org: org.Name, Peer: peer.URL(), ChainCodes: []ChainCodeInfo{}}
+			for _, cc := range resp.Chaincodes {
+				item.ChainCodes = append(item.ChainCodes, ChainCodeInfo{Name: cc.Name, Version: cc.Version, Path: cc.Path})
+			}
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// InstantiatedChainCodes 查询通道上已实例化的链码
+func (setup *FabricSetup) InstantiatedChainCodes(channelID string) ([]ChainCodeInfo, error) {
+	return setup.InstantiatedChainCodesContext(context.Background(), channelID)
+}
+
+// InstantiatedChainCodesContext 查询通道上已实例化的链码
+func (setup *FabricSetup) InstantiatedChainCodesContext(ctx context.Context, channelID string) ([]ChainCodeInfo, error) {
+	if err := setup.requireState(StateChannelJoined); err != nil {
+		return nil, err
+	}
+
+	resp, err := setup.Util.admin.QueryInstantiatedChaincodes(channelID, resmgmtOptions(ctx, resmgmt.WithRetry(retry.DefaultResMgmtOpts))...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to query instantiated chaincodes")
+	}
+	result := []ChainCodeInfo{}
+	for _, cc := range resp.Chaincodes {
+		result = append(result, ChainCodeInfo{Name: cc.Name, Version: cc.Version, Path: cc.Path})
+	}
+	return result, nil
+}
+
+// instantiatedVersion 返回通道上链码当前实例化的版本, 未实例化时为空
+func (setup *FabricSetup) instantiatedVersion(ctx context.Context, channelID, ccName string) (string, error) {
+	chainCodes, err := setup.InstantiatedChainCodesContext(ctx, channelID)
+	if err != nil {
+		return "", err
+	}
+	for _, cc := range chainCodes {
+		if cc.Name == ccName {
+			return cc.Version, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,23 @@
+package blockchain
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// 日志字段
+const (
+	LogFieldOrg       = "org"
+	LogFieldChannel   = "channel"
+	LogFieldChaincode = "chaincode"
+	LogFieldPeer      = "peer"
+	LogFieldTxID      = "tx_id"
+)
+
+// log 返回带有组织字段的日志, 未设置 Logger 时使用 logrus 默认日志
+func (setup *FabricSetup) log() logrus.FieldLogger {
+	var logger logrus.FieldLogger = logrus.StandardLogger()
+	if setup.Logger != nil {
+		logger = setup.Logger
+	}
+	return logger.WithField(LogFieldOrg, setup.Org.Name)
+}
@@ -0,0 +1,44 @@
+package blockchain
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/common/cauthdsl"
+	cb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+	mspproto "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/msp"
+	"github.com/pkg/errors"
+)
+
+// ParsePolicy 解析背书策略表达式, 如 AND('Org1MSP.member','Org2MSP.member') 或 OutOf(2, 'Org1MSP.peer', ...),
+// 策略中出现的MSP ID必须属于 mspIDs
+func ParsePolicy(expression string, mspIDs []string) (*cb.SignaturePolicyEnvelope, error) {
+	policy, err := cauthdsl.FromString(expression)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid endorsement policy "+expression)
+	}
+
+	known := make(map[string]bool)
+	for _, id := range mspIDs {
+		known[id] = true
+	}
+	for _, principal := range policy.Identities {
+		if principal.PrincipalClassification != mspproto.MSPPrincipal_ROLE {
+			continue
+		}
+		role := &mspproto.MSPRole{}
+		if err := proto.Unmarshal(principal.Principal, role); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal policy principal")
+		}
+		if !known[role.MspIdentifier] {
+			return nil, errors.Errorf("endorsement policy references unknown MSP %s", role.MspIdentifier)
+		}
+	}
+	return policy, nil
+}
+
+// policyFor 返回链码的背书策略, ChainCode.Policy 为空时任一参与组织的成员即可背书
+func (setup *FabricSetup) policyFor(chainCode ChainCode) (*cb.SignaturePolicyEnvelope, error) {
+	if chainCode.Policy == "" {
+		return cauthdsl.SignedByAnyMember(setup.MspIDs()), nil
+	}
+	return ParsePolicy(chainCode.Policy, setup.MspIDs())
+}
@@ -0,0 +1,45 @@
+package blockchain
+
+import (
+	"testing"
+)
+
+func TestParsePolicy(t *testing.T) {
+	mspIDs := []string{"Org1MSP", "Org2MSP"}
+	tests := []struct {
+		name       string
+		expression string
+		identities int
+		wantErr    bool
+	}{
+		{"single member", "OR('Org1MSP.member')", 1, false},
+		{"both orgs", "AND('Org1MSP.member','Org2MSP.member')", 2, false},
+		{"out of", "OutOf(1, 'Org1MSP.peer', 'Org2MSP.peer')", 2, false},
+		{"admin role", "OR('Org2MSP.admin')", 1, false},
+		{"unknown msp", "AND('Org1MSP.member','Org3MSP.member')", 0, true},
+		{"syntax error", "AND('Org1MSP.member'", 0, true},
+		{"unknown role", "OR('Org1MSP.owner')", 0, true},
+		{"empty", "", 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy, err := ParsePolicy(test.expression, mspIDs)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("ParsePolicy(%q) succeeded, want an error", test.expression)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePolicy(%q): %v", test.expression, err)
+			}
+			if len(policy.Identities) != test.identities {
+				t.Errorf("identities = %d, want %d", len(policy.Identities), test.identities)
+			}
+			if policy.Rule == nil {
+				t.Error("policy has no rule")
+			}
+		})
+	}
+}
@@ -1,66 +1,175 @@
 package blockchain
 
 import (
+	"context"
 	"fmt"
+
 	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
 	"github.com/hyperledger/fabric-sdk-go/pkg/client/event"
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/ledger"
 	mspclient "github.com/hyperledger/fabric-sdk-go/pkg/client/msp"
 	"github.com/hyperledger/fabric-sdk-go/pkg/client/resmgmt"
-	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/retry"
-	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/msp"
 	"github.com/hyperledger/fabric-sdk-go/pkg/core/config"
-	packager "github.com/hyperledger/fabric-sdk-go/pkg/fab/ccpackager/gopackager"
 	"github.com/hyperledger/fabric-sdk-go/pkg/fabsdk"
-	"github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/common/cauthdsl"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// State FabricSetup 的生命周期状态
+//
+// 状态只会向前推进:
+//
+//	StateNew -> StateSDKCreated -> StateChannelJoined -> StateChainCodeReady
+//
+// Initialize 负责 StateNew 到 StateChannelJoined, InstallAndInstantiateCC 负责 StateChainCodeReady
+type State int
+
+const (
+	// StateNew 尚未初始化
+	StateNew State = iota
+	// StateSDKCreated sdk与资源管理客户端已创建
+	StateSDKCreated
+	// StateChannelJoined 通道已创建并加入, channel/event/ledger 客户端可用
+	StateChannelJoined
+	// StateChainCodeReady 链码已安装并实例化
+	StateChainCodeReady
 )
 
+func (s State) String() string {
+	switch s {
+	case StateNew:
+		return "new"
+	case StateSDKCreated:
+		return "sdk created"
+	case StateChannelJoined:
+		return "channel joined"
+	case StateChainCodeReady:
+		return "chaincode ready"
+	}
+	return fmt.Sprintf("unknown(%d)", int(s))
+}
+
 // FabricSetup implementation
+//
+// Org 为客户端所属组织, 交易默认以 Org.User 身份提交;
+// Orgs 为参与通道的所有组织(包含 Org), 各组织管理员分别负责加入通道与安装链码;
+// OrdererOrg 为排序组织, 修改通道排序配置的更新需要其管理员签名, 未设置时拒绝此类更新;
+// ChannelConfig 为默认通道, Channels 为需要同时创建并加入的其他通道;
+// Logger 为空时使用 logrus 默认日志
 type FabricSetup struct {
-	initialized   bool
 	ConfigFile    string
 	Org           Org
+	Orgs          []Org
+	OrdererOrg    Org
 	ChannelConfig ChannelConfig
+	Channels      []ChannelConfig
 	ChainCode     ChainCode
+	ClientCache   ClientCacheConfig
+	Logger        logrus.FieldLogger
 	Util          Util
+	state         State
 }
 
 // Org 组织信息
 type Org struct {
-	ID        string
-	Admin     string
-	Name      string
-	User      string
+	ID      string
+	MspID   string
+	Admin   string
+	Name    string
+	User    string
 	OrderID string
 }
 
 // ChannelConfig 通道配置信息
+//
+// AnchorPeers 为各组织(按组织名)的锚节点地址(host:port)
 type ChannelConfig struct {
-	ID       string
-	FilePath string
+	ID          string
+	FilePath    string
+	AnchorPeers map[string][]string
 }
 
 // ChainCode 链码信息
+//
+// Policy 为背书策略表达式, 如 AND('Org1MSP.member','Org2MSP.member'), 为空时任一参与组织的成员即可背书;
+// CollectionsFile 为私有数据集合配置(JSON)文件路径, 为空时不使用私有数据
 type ChainCode struct {
-	ID      string
-	Version string
-	GoPath  string
-	SrcPath string
+	ID              string
+	Version         string
+	GoPath          string
+	SrcPath         string
+	Policy          string
+	CollectionsFile string
 }
 
 // Util 工具
 type Util struct {
 	client *channel.Client
 	admin  *resmgmt.Client
+	admins map[string]*resmgmt.Client
 	sdk    *fabsdk.FabricSDK
 	event  *event.Client
+	ledger *ledger.Client
+	msp    *mspclient.Client
+
+	clients   clientCache
+	channels  channelManager
+	secrets   secretCache
+	approvals configApprovals
+}
+
+// NewFabricSetup 为一组组织创建 FabricSetup, 第一个组织为客户端所属组织
+func NewFabricSetup(configFile string, orgs []Org, channelConfig ChannelConfig) (*FabricSetup, error) {
+	if len(orgs) == 0 {
+		return nil, errors.New("at least one organization is required")
+	}
+	return &FabricSetup{
+		ConfigFile:    configFile,
+		Org:           orgs[0],
+		Orgs:          orgs,
+		ChannelConfig: channelConfig,
+	}, nil
+}
+
+// AllOrgs 返回参与通道的所有组织, 未设置 Orgs 时只包含 Org
+func (setup *FabricSetup) AllOrgs() []Org {
+	if len(setup.Orgs) == 0 {
+		return []Org{setup.Org}
+	}
+	return setup.Orgs
+}
+
+// MspIDs 返回所有组织的MSP ID
+func (setup *FabricSetup) MspIDs() []string {
+	var ids []string
+	for _, org := range setup.AllOrgs() {
+		ids = append(ids, org.MspID)
+	}
+	return ids
+}
+
+// State 返回当前的生命周期状态
+func (setup *FabricSetup) State() State {
+	return setup.state
+}
+
+// requireState 检查当前状态是否已达到 state
+func (setup *FabricSetup) requireState(state State) error {
+	if setup.state < state {
+		return errors.Errorf("fabric setup is %s, expected %s", setup.state, state)
+	}
+	return nil
 }
 
 // Initialize reads the configuration file and sets up the client, chain and event hub
 func (setup *FabricSetup) Initialize() error {
+	return setup.InitializeContext(context.Background())
+}
 
+// InitializeContext 初始化 sdk 并创建, 加入默认通道
+func (setup *FabricSetup) InitializeContext(ctx context.Context) error {
 	// Add parameters for the initialization
-	if setup.initialized {
+	if setup.state != StateNew {
 		return errors.New("sdk already initialized")
 	}
 
@@ -70,90 +179,137 @@ func (setup *FabricSetup) Initialize() error {
 		return errors.WithMessage(err, "failed to create SDK")
 	}
 	setup.Util.sdk = sdk
-	fmt.Println("SDK created")
+	setup.log().Info("SDK created")
 
 	// The resource management client is responsible for managing channels (create/update channel)
-	resourceManagerClientContext := setup.Util.sdk.Context(fabsdk.WithUser(setup.Org.Admin), fabsdk.WithOrg(setup.Org.Name))
-	if err != nil {
-		return errors.WithMessage(err, "failed to load Admin identity")
-	}
-	resMgmtClient, err := resmgmt.New(resourceManagerClientContext)
-	if err != nil {
-		return errors.WithMessage(err, "failed to create channel management client from Admin identity")
+	// Each org's admin manages its own peers
+	setup.Util.admins = make(map[string]*resmgmt.Client)
+	for _, org := range setup.AllOrgs() {
+		resourceManagerClientContext := setup.Util.sdk.Context(fabsdk.WithUser(org.Admin), fabsdk.WithOrg(org.Name))
+		resMgmtClient, err := resmgmt.New(resourceManagerClientContext)
+		if err != nil {
+			return errors.WithMessage(err, "failed to create channel management client from Admin identity of "+org.Name)
+		}
+		setup.Util.admins[org.Name] = resMgmtClient
+		setup.log().WithField(LogFieldOrg, org.Name).Info("Resource management client created")
 	}
-	setup.Util.admin = resMgmtClient
-	fmt.Println("Resource management client created")
+	setup.Util.admin = setup.Util.admins[setup.Org.Name]
 
 	// The MSP client allow us to retrieve user information from their identity, like its signing identity which we will need to save the channel
-	mspClient, err := mspclient.New(sdk.Context(), mspclient.WithOrg(setup.Org.Name))
+	// It is also used to manage identities through the org's Fabric CA
+	setup.Util.msp, err = mspclient.New(sdk.Context(), mspclient.WithOrg(setup.Org.Name))
 	if err != nil {
 		return errors.WithMessage(err, "failed to create MSP client")
 	}
-	adminIdentity, err := mspClient.GetSigningIdentity(setup.Org.Admin)
+	setup.log().Info("MSP client created")
+	setup.state = StateSDKCreated
+
+	// Create the channels from their .tx files and make every org join them
+	for _, channelConfig := range setup.AllChannels() {
+		results, err := setup.CreateChannelContext(ctx, channelConfig)
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			if result.Error != "" {
+				return errors.Errorf("failed to join %s to channel %s: %s", result.Peer, channelConfig.ID, result.Error)
+			}
+		}
+
+		// Anchor peers let the orgs gossip with each other
+		anchors, err := setup.UpdateAnchorPeersContext(ctx, channelConfig)
+		if err != nil {
+			return err
+		}
+		for _, anchor := range anchors {
+			setup.log().WithFields(logrus.Fields{LogFieldOrg: anchor.Org, LogFieldChannel: anchor.Channel}).Info("Anchor peers ", anchor.Status)
+		}
+	}
+
+	// Channel client is used to query and execute transactions
+	clientContext := setup.Util.sdk.ChannelContext(setup.ChannelConfig.ID, fabsdk.WithUser(setup.Org.User))
+	setup.Util.client, err = channel.New(clientContext)
+	if err != nil {
+		return errors.WithMessage(err, "failed to create new channel client")
+	}
+	setup.Util.clients.config = setup.ClientCache
+	setup.log().WithField(LogFieldChannel, setup.ChannelConfig.ID).Info("Channel client created")
+
+	// Creation of the client which will enables access to our channel events
+	setup.Util.event, err = event.New(clientContext)
 	if err != nil {
-		return errors.WithMessage(err, "failed to get admin signing identity")
+		return errors.WithMessage(err, "failed to create new event client")
 	}
-	req := resmgmt.SaveChannelRequest{ChannelID: setup.ChannelConfig.ID, ChannelConfigPath: setup.ChannelConfig.FilePath, SigningIdentities: []msp.SigningIdentity{adminIdentity}}
-	txID, err := setup.Util.admin.SaveChannel(req, resmgmt.WithOrdererEndpoint(setup.Org.OrderID))
-	if err != nil || txID.TransactionID == "" {
-		return errors.WithMessage(err, "failed to save channel")
+	setup.log().WithField(LogFieldChannel, setup.ChannelConfig.ID).Info("Event client created")
+
+	// Ledger client is used to query blocks and transactions
+	setup.Util.ledger, err = ledger.New(clientContext)
+	if err != nil {
+		return errors.WithMessage(err, "failed to create new ledger client")
 	}
-	fmt.Println("Channel created")
+	setup.log().WithField(LogFieldChannel, setup.ChannelConfig.ID).Info("Ledger client created")
 
-	// Make admin user join the previously created channel
-	if err = setup.Util.admin.JoinChannel(setup.ChannelConfig.ID, resmgmt.WithRetry(retry.DefaultResMgmtOpts), resmgmt.WithOrdererEndpoint(setup.Org.OrderID)); err != nil {
-		return errors.WithMessage(err, "failed to make admin join channel")
+	setup.Util.channels.channels = map[string]*channelClients{
+		setup.ChannelConfig.ID: {channel: setup.Util.client, event: setup.Util.event, ledger: setup.Util.ledger},
 	}
-	fmt.Println("Channel joined")
 
-	fmt.Println("Initialization Successful")
-	setup.initialized = true
+	setup.log().Info("Initialization Successful")
+	setup.state = StateChannelJoined
 	return nil
 }
 
-// InstallAndInstantiateCC 安装与初始化ChainCode
+// InstallAndInstantiateCC 安装与初始化ChainCode, 已安装或已实例化的步骤会被跳过,
+// 通道上已实例化旧版本时升级到 ChainCode.Version
 func (setup *FabricSetup) InstallAndInstantiateCC() error {
-	chainCode := setup.ChainCode
+	return setup.InstallAndInstantiateCCContext(context.Background())
+}
 
-	// Create the ChainCode package that will be sent to the peers
-	ccPkg, err := packager.NewCCPackage(chainCode.SrcPath, chainCode.GoPath)
-	if err != nil {
-		return errors.WithMessage(err, "failed to create ChainCode package")
+// InstallAndInstantiateCCContext 安装并实例化 ChainCode
+func (setup *FabricSetup) InstallAndInstantiateCCContext(ctx context.Context) error {
+	if err := setup.requireState(StateChannelJoined); err != nil {
+		return err
 	}
-	fmt.Println("ccPkg created")
+	chainCode := setup.ChainCode
+	log := setup.log().WithFields(logrus.Fields{LogFieldChannel: setup.ChannelConfig.ID, LogFieldChaincode: chainCode.ID})
 
-	// Install example cc to org peers
-	installCCReq := resmgmt.InstallCCRequest{Name: chainCode.ID, Path: chainCode.SrcPath, Version: chainCode.Version, Package: ccPkg}
-	_, err = setup.Util.admin.InstallCC(installCCReq, resmgmt.WithRetry(retry.DefaultResMgmtOpts))
+	// Install example cc to every org's peers, peers that already have this version are skipped
+	results, err := setup.InstallChainCodeContext(ctx, chainCode, nil, nil)
 	if err != nil {
 		return errors.WithMessage(err, "failed to install chaincode")
 	}
-	fmt.Println("ChainCode installed")
-
-	// Set up ChainCode policy
-	ccPolicy := cauthdsl.SignedByAnyMember([]string{setup.Org.ID})
-
-	resp, err := setup.Util.admin.InstantiateCC(setup.ChannelConfig.ID, resmgmt.InstantiateCCRequest{Name: chainCode.ID, Path: chainCode.SrcPath, Version: chainCode.Version, Args: [][]byte{[]byte("init")}, Policy: ccPolicy})
-	if err != nil || resp.TransactionID == "" {
-		return errors.WithMessage(err, "failed to instantiate the chaincode")
+	for _, result := range results {
+		if result.Error != "" {
+			return errors.Errorf("failed to install chaincode on %s: %s", result.Peer, result.Error)
+		}
 	}
-	fmt.Println("ChainCode instantiated")
+	log.Info("ChainCode installed")
 
-	// Channel client is used to query and execute transactions
-	clientContext := setup.Util.sdk.ChannelContext(setup.ChannelConfig.ID, fabsdk.WithUser(setup.Org.User))
-	setup.Util.client, err = channel.New(clientContext)
+	version, err := setup.instantiatedVersion(ctx, setup.ChannelConfig.ID, chainCode.ID)
 	if err != nil {
-		return errors.WithMessage(err, "failed to create new channel client")
+		return err
 	}
-	fmt.Println("Channel client created")
-
-	// Creation of the client which will enables access to our channel events
-	setup.Util.event, err = event.New(clientContext)
-	if err != nil {
-		return errors.WithMessage(err, "failed to create new event client")
+	args := [][]byte{[]byte("init")}
+	switch version {
+	case chainCode.Version:
+		log.Info("ChainCode has instantiated")
+	case "":
+		if _, err = setup.InstantiateChainCodeContext(ctx, setup.ChannelConfig.ID, chainCode, args); err != nil {
+			return err
+		}
+	default:
+		if _, err = setup.UpgradeChainCodeContext(ctx, setup.ChannelConfig.ID, chainCode, args); err != nil {
+			return err
+		}
 	}
-	fmt.Println("Event client created")
 
-	fmt.Println("ChainCode Installation & Instantiation Successful")
+	log.Info("ChainCode Installation & Instantiation Successful")
+	setup.state = StateChainCodeReady
 	return nil
 }
+
+// Close 释放sdk占用的资源
+func (setup *FabricSetup) Close() {
+	if setup.Util.sdk != nil {
+		setup.Util.sdk.Close()
+	}
+}
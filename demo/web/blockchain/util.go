@@ -0,0 +1,20 @@
+package blockchain
+
+// Msg 接口统一返回结构
+type Msg struct {
+	StatusCode int         `json:"status_code"`
+	Code       string      `json:"code,omitempty"`
+	Message    string      `json:"message"`
+	Data       interface{} `json:"data"`
+}
+
+// GetParams 将字符串参数转换为链码调用参数
+func GetParams(args []string) [][]byte {
+	var res [][]byte
+
+	for _, item := range args {
+		res = append(res, []byte(item))
+	}
+
+	return res
+}
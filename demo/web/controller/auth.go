@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"net/http"
+
+	"bcfish.cn/demo/web/blockchain"
+	"bcfish.cn/demo/web/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// Auth 登录接口
+type Auth struct {
+	Setup *blockchain.FabricSetup
+}
+
+// LoginRequest 登录请求, 使用CA的登记ID与密码
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// Login 校验用户密码(首次登录时向CA登记), 成功后签发token
+// POST /auth/login
+func (ctl *Auth) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := ctl.Setup.Login(req.Username, req.Password); err != nil {
+		failure(c, http.StatusUnauthorized, err)
+		return
+	}
+
+	identity, err := ctl.Setup.Identity(req.Username)
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	// 带 admin=true 属性的用户(见 RegisterAdmin)与CA注册员拥有管理员角色
+	roles := []string{middleware.RoleMember}
+	for _, attr := range identity.Attributes {
+		if (attr.Name == "admin" && attr.Value == "true") || (attr.Name == "hf.Registrar.Roles" && attr.Value != "") {
+			roles = append(roles, middleware.RoleAdmin)
+			break
+		}
+	}
+
+	token, err := middleware.GenerateToken(req.Username, ctl.Setup.Org.Name, roles)
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, gin.H{"token": token, "enrollment_id": req.Username, "org": ctl.Setup.Org.Name, "roles": roles})
+}
@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"net/http"
+
+	"bcfish.cn/demo/web/blockchain"
+	"bcfish.cn/demo/web/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// Channels 通道管理接口
+type Channels struct {
+	Setup *blockchain.FabricSetup
+}
+
+// CreateChannelRequest 创建通道请求, file_path 为通道配置交易(.tx)文件路径,
+// anchor_peers 为各组织的锚节点地址(host:port)
+type CreateChannelRequest struct {
+	ID          string              `json:"id" binding:"required"`
+	FilePath    string              `json:"file_path" binding:"required"`
+	AnchorPeers map[string][]string `json:"anchor_peers"`
+}
+
+// List 各peer已加入的通道
+// GET /channels
+func (ctl *Channels) List(c *gin.Context) {
+	peers, err := ctl.Setup.QueryChannelsContext(c.Request.Context())
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, gin.H{"joined": ctl.Setup.JoinedChannels(), "peers": peers})
+}
+
+// Create 创建通道并让所有组织加入, 返回每个peer的加入结果
+// POST /channels
+func (ctl *Channels) Create(c *gin.Context) {
+	var req CreateChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+
+	channelConfig := blockchain.ChannelConfig{ID: req.ID, FilePath: req.FilePath, AnchorPeers: req.AnchorPeers}
+	results, err := ctl.Setup.CreateChannelContext(c.Request.Context(), channelConfig)
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, gin.H{"channel": channelConfig, "peers": results})
+}
+
+// AnchorPeers 提交通道上各组织的锚节点更新, 锚节点已是配置值的组织跳过
+// POST /channels/:channel/anchor-peers
+func (ctl *Channels) AnchorPeers(c *gin.Context) {
+	channelConfig, ok := ctl.Setup.JoinedChannel(c.Param("channel"))
+	if !ok {
+		failure(c, http.StatusNotFound, errors.Errorf("channel %s is not joined", c.Param("channel")))
+		return
+	}
+
+	results, err := ctl.Setup.UpdateAnchorPeersContext(c.Request.Context(), channelConfig)
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, results)
+}
+
+// ConfigUpdateRequest 通道配置更新请求, config 为修改后的 GET /channels/:channel/config 结果(省略的字段不变),
+// dry_run 时只返回计算得到的更新交易. 更新只由当前用户所属组织的管理员签名,
+// 需要多个组织签名时先 dry_run, 再由各组织管理员分别签名(sign)后提交(submit)
+type ConfigUpdateRequest struct {
+	Config blockchain.ChannelConfigInfo `json:"config"`
+	DryRun bool                         `json:"dry_run"`
+}
+
+// ConfigEnvelopeRequest 已计算的配置更新交易(base64)
+type ConfigEnvelopeRequest struct {
+	Envelope []byte `json:"envelope" binding:"required"`
+}
+
+// Config 通道配置
+// GET /channels/:channel/config
+func (ctl *Channels) Config(c *gin.Context) {
+	info, err := ctl.Setup.ChannelConfigurationContext(c.Request.Context(), c.Param("channel"))
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, info)
+}
+
+// UpdateConfig 根据修改后的配置计算更新, 以当前用户所属组织管理员签名后提交
+// POST /channels/:channel/config
+func (ctl *Channels) UpdateConfig(c *gin.Context) {
+	var req ConfigUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+
+	update, err := ctl.Setup.ComputeChannelConfigUpdateContext(c.Request.Context(), c.Param("channel"), &req.Config)
+	if err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.DryRun {
+		success(c, update)
+		return
+	}
+
+	update, err = ctl.Setup.SubmitChannelConfigUpdateContext(c.Request.Context(), update, middleware.GetOrg(c))
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, update)
+}
+
+// SignConfig 以当前用户所属组织的管理员签名配置更新交易, 返回已签名的组织
+// POST /channels/:channel/config/sign
+func (ctl *Channels) SignConfig(c *gin.Context) {
+	var req ConfigEnvelopeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+
+	update := &blockchain.ChannelConfigUpdate{ChannelID: c.Param("channel"), Envelope: req.Envelope}
+	update, err := ctl.Setup.SignChannelConfigUpdate(update, middleware.GetOrg(c))
+	if err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+	success(c, update)
+}
+
+// SubmitConfig 以当前用户所属组织的管理员签名并提交配置更新交易, 已通过 sign 签名的组织一并签名
+// POST /channels/:channel/config/submit
+func (ctl *Channels) SubmitConfig(c *gin.Context) {
+	var req ConfigEnvelopeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+
+	update := &blockchain.ChannelConfigUpdate{ChannelID: c.Param("channel"), Envelope: req.Envelope}
+	update, err := ctl.Setup.SignChannelConfigUpdate(update, middleware.GetOrg(c))
+	if err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+	update, err = ctl.Setup.SubmitChannelConfigUpdateContext(c.Request.Context(), update, middleware.GetOrg(c))
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, update)
+}
@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"io"
+	"net/http"
+	"regexp"
+
+	"bcfish.cn/demo/web/blockchain"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+)
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// Events 事件推送接口, 请求带 Upgrade: websocket 头时使用 WebSocket, 否则使用 Server-Sent Events
+type Events struct {
+	Hub *blockchain.EventHub
+}
+
+// Chaincode 订阅链码事件
+// GET /events/chaincode/:cc?filter=regex
+func (ctl *Events) Chaincode(c *gin.Context) {
+	filter := c.DefaultQuery("filter", ".*")
+	if _, err := regexp.Compile(filter); err != nil {
+		failure(c, http.StatusBadRequest, errors.Wrap(err, "invalid filter"))
+		return
+	}
+
+	sub, err := ctl.Hub.SubscribeChaincode(c.Param("cc"), filter)
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	stream(c, sub)
+}
+
+// Blocks 订阅区块事件, filtered=true 时只推送过滤区块
+// GET /events/blocks?filtered=true
+func (ctl *Events) Blocks(c *gin.Context) {
+	var (
+		sub *blockchain.Subscription
+		err error
+	)
+	if c.Query("filtered") == "true" {
+		sub, err = ctl.Hub.SubscribeFilteredBlocks()
+	} else {
+		sub, err = ctl.Hub.SubscribeBlocks()
+	}
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	stream(c, sub)
+}
+
+// stream 推送事件直到客户端断开
+func stream(c *gin.Context, sub *blockchain.Subscription) {
+	defer sub.Close()
+
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		streamWebSocket(c, sub)
+		return
+	}
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			c.SSEvent(e.Type, e)
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+func streamWebSocket(c *gin.Context, sub *blockchain.Subscription) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// 读取客户端消息以感知断开
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+			if err := conn.WriteJSON(e); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"bcfish.cn/demo/web/blockchain"
+	"bcfish.cn/demo/web/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+	"github.com/pkg/errors"
+)
+
+// ExampleCC example_cc 链码接口
+type ExampleCC struct {
+	Setup *blockchain.FabricSetup
+}
+
+// MoveRequest 转账请求
+type MoveRequest struct {
+	From   string `json:"from" binding:"required"`
+	To     string `json:"to" binding:"required"`
+	Amount int    `json:"amount" binding:"required"`
+}
+
+// Move 从 from 转账 amount 到 to
+// POST /cc/example_cc/move
+func (ctl *ExampleCC) Move(c *gin.Context) {
+	var req MoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+
+	args := []string{req.From, req.To, strconv.Itoa(req.Amount)}
+	resp, err := ctl.Setup.ExecuteAsContext(c.Request.Context(), middleware.GetOrg(c), middleware.GetEnrollmentID(c), channel.Request{ChaincodeID: ctl.Setup.ChainCode.ID, Fcn: "move", Args: blockchain.GetParams(args)})
+	if err != nil {
+		invokeFailure(c, err)
+		return
+	}
+
+	success(c, gin.H{"tx_id": resp.TransactionID})
+}
+
+// OpenAccountRequest 开户请求, amount 为初始余额, currency 为空时使用链码默认币种
+type OpenAccountRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Amount   int    `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// OpenAccount 创建账户, 当前用户为账户所有者, 账户已存在时失败
+// POST /cc/example_cc/accounts
+func (ctl *ExampleCC) OpenAccount(c *gin.Context) {
+	var req OpenAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.Amount < 0 {
+		failure(c, http.StatusBadRequest, errors.New("amount must not be negative"))
+		return
+	}
+
+	args := []string{req.Name, strconv.Itoa(req.Amount), req.Currency}
+	resp, err := ctl.Setup.ExecuteAsContext(c.Request.Context(), middleware.GetOrg(c), middleware.GetEnrollmentID(c), channel.Request{ChaincodeID: ctl.Setup.ChainCode.ID, Fcn: "open", Args: blockchain.GetParams(args)})
+	if err != nil {
+		invokeFailure(c, err)
+		return
+	}
+
+	success(c, gin.H{"tx_id": resp.TransactionID})
+}
+
+// AccountOwner 开户的客户端身份
+type AccountOwner struct {
+	MSPID string `json:"mspId"`
+	ID    string `json:"id"`
+}
+
+// Account 链码中的账户文档
+type Account struct {
+	DocType   string       `json:"docType"`
+	ID        string       `json:"id"`
+	Owner     AccountOwner `json:"owner"`
+	Balance   int          `json:"balance"`
+	Currency  string       `json:"currency"`
+	CreatedAt time.Time    `json:"createdAt"`
+	UpdatedAt time.Time    `json:"updatedAt"`
+}
+
+// Account 查询账户
+// GET /cc/example_cc/accounts/:name
+func (ctl *ExampleCC) Account(c *gin.Context) {
+	name := c.Param("name")
+
+	resp, err := ctl.Setup.QueryAsContext(c.Request.Context(), middleware.GetOrg(c), middleware.GetEnrollmentID(c), channel.Request{ChaincodeID: ctl.Setup.ChainCode.ID, Fcn: "query", Args: blockchain.GetParams([]string{name})})
+	if err != nil {
+		invokeFailure(c, err)
+		return
+	}
+
+	var account Account
+	if err := json.Unmarshal(resp.Payload, &account); err != nil {
+		failure(c, http.StatusInternalServerError, errors.Wrap(err, "invalid account document"))
+		return
+	}
+	success(c, account)
+}
+
+// AccountPage 一页账户, bookmark 用于查询下一页, 最后一页时为空
+type AccountPage struct {
+	Records  []Account `json:"records"`
+	Bookmark string    `json:"bookmark"`
+	Count    int32     `json:"count"`
+}
+
+// ListAccounts 按账户名顺序分页查询全部账户
+// GET /cc/example_cc/accounts?page_size=&bookmark=
+func (ctl *ExampleCC) ListAccounts(c *gin.Context) {
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil || pageSize <= 0 {
+		failure(c, http.StatusBadRequest, errors.New("invalid page_size"))
+		return
+	}
+
+	ctl.accountPage(c, "list", nil, pageSize, c.Query("bookmark"))
+}
+
+// QueryAccountsRequest 账户富查询请求, query 为 CouchDB Mango 查询或 selector,
+// 如 {"balance":{"$gt":100},"owner.mspId":"Org1MSP"}
+type QueryAccountsRequest struct {
+	Query    json.RawMessage `json:"query" binding:"required"`
+	PageSize int             `json:"page_size"`
+	Bookmark string          `json:"bookmark"`
+}
+
+// QueryAccounts 按 CouchDB 查询条件分页查询账户
+// POST /cc/example_cc/accounts/query
+func (ctl *ExampleCC) QueryAccounts(c *gin.Context) {
+	var req QueryAccountsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.PageSize < 0 {
+		failure(c, http.StatusBadRequest, errors.New("invalid page_size"))
+		return
+	}
+	if req.PageSize == 0 {
+		req.PageSize = 20
+	}
+
+	ctl.accountPage(c, "richQuery", []string{string(req.Query)}, req.PageSize, req.Bookmark)
+}
+
+// accountPage 调用链码的分页查询, args 之后追加 pageSize 与 bookmark
+func (ctl *ExampleCC) accountPage(c *gin.Context, fcn string, args []string, pageSize int, bookmark string) {
+	args = append(args, strconv.Itoa(pageSize))
+	if bookmark != "" {
+		args = append(args, bookmark)
+	}
+	resp, err := ctl.Setup.QueryAsContext(c.Request.Context(), middleware.GetOrg(c), middleware.GetEnrollmentID(c), channel.Request{ChaincodeID: ctl.Setup.ChainCode.ID, Fcn: fcn, Args: blockchain.GetParams(args)})
+	if err != nil {
+		invokeFailure(c, err)
+		return
+	}
+
+	var page AccountPage
+	if err := json.Unmarshal(resp.Payload, &page); err != nil {
+		failure(c, http.StatusInternalServerError, errors.Wrap(err, "invalid account page"))
+		return
+	}
+	success(c, page)
+}
+
+// HistoryRecord 账户的一次修改, value 为交易写入的账户文档
+type HistoryRecord struct {
+	TxID      string          `json:"txId"`
+	Timestamp time.Time       `json:"timestamp"`
+	Value     json.RawMessage `json:"value"`
+	IsDelete  bool            `json:"isDelete"`
+}
+
+// HistoryPage 一页账户修改记录, 按时间从早到晚
+type HistoryPage struct {
+	Records []HistoryRecord `json:"records"`
+	HasMore bool            `json:"hasMore"`
+}
+
+// AccountHistory 账户的修改历史, limit 为 0 时返回全部
+// GET /cc/example_cc/accounts/:name/history?offset=&limit=
+func (ctl *ExampleCC) AccountHistory(c *gin.Context) {
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		failure(c, http.StatusBadRequest, errors.New("invalid offset"))
+		return
+	}
+	limit, err := queryLimit(c)
+	if err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+
+	args := []string{c.Param("name")}
+	if limit > 0 {
+		args = append(args, strconv.Itoa(offset), strconv.Itoa(limit))
+	}
+	resp, err := ctl.Setup.QueryAsContext(c.Request.Context(), middleware.GetOrg(c), middleware.GetEnrollmentID(c), channel.Request{ChaincodeID: ctl.Setup.ChainCode.ID, Fcn: "history", Args: blockchain.GetParams(args)})
+	if err != nil {
+		invokeFailure(c, err)
+		return
+	}
+
+	var page HistoryPage
+	if err := json.Unmarshal(resp.Payload, &page); err != nil {
+		failure(c, http.StatusInternalServerError, errors.Wrap(err, "invalid history result"))
+		return
+	}
+	success(c, page)
+}
+
+// MigrateRequest 账户迁移请求, names 为空时迁移全部旧格式余额
+type MigrateRequest struct {
+	Names []string `json:"names"`
+}
+
+// MigrateAccounts 将旧格式(整数)余额转换为账户文档(不设置所有者), 只有管理员可以迁移, 返回迁移的账户
+// POST /cc/example_cc/accounts/migrate
+func (ctl *ExampleCC) MigrateAccounts(c *gin.Context) {
+	var req MigrateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := ctl.Setup.ExecuteAsContext(c.Request.Context(), middleware.GetOrg(c), middleware.GetEnrollmentID(c), channel.Request{ChaincodeID: ctl.Setup.ChainCode.ID, Fcn: "migrate", Args: blockchain.GetParams(req.Names)})
+	if err != nil {
+		invokeFailure(c, err)
+		return
+	}
+
+	var migrated []string
+	if err := json.Unmarshal(resp.Payload, &migrated); err != nil {
+		failure(c, http.StatusInternalServerError, errors.Wrap(err, "invalid migrate result"))
+		return
+	}
+	success(c, gin.H{"tx_id": resp.TransactionID, "migrated": migrated})
+}
+
+// DeleteAccount 删除账户
+// DELETE /cc/example_cc/accounts/:name
+func (ctl *ExampleCC) DeleteAccount(c *gin.Context) {
+	name := c.Param("name")
+
+	resp, err := ctl.Setup.ExecuteAsContext(c.Request.Context(), middleware.GetOrg(c), middleware.GetEnrollmentID(c), channel.Request{ChaincodeID: ctl.Setup.ChainCode.ID, Fcn: "delete", Args: blockchain.GetParams([]string{name})})
+	if err != nil {
+		invokeFailure(c, err)
+		return
+	}
+
+	success(c, gin.H{"tx_id": resp.TransactionID})
+}
+
+// PrivateRequest 私有数据写入请求, values 通过 transient 传递, 不会写入交易
+type PrivateRequest struct {
+	Values map[string]string `json:"values" binding:"required"`
+}
+
+// PutPrivate 写入私有数据集合
+// POST /cc/example_cc/private/:collection
+func (ctl *ExampleCC) PutPrivate(c *gin.Context) {
+	var req PrivateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+
+	transient := make(map[string][]byte, len(req.Values))
+	for key, value := range req.Values {
+		transient[key] = []byte(value)
+	}
+
+	request := channel.Request{ChaincodeID: ctl.Setup.ChainCode.ID, Fcn: "putPrivate", Args: blockchain.GetParams([]string{c.Param("collection")}), TransientMap: transient}
+	resp, err := ctl.Setup.ExecuteAsContext(c.Request.Context(), middleware.GetOrg(c), middleware.GetEnrollmentID(c), request)
+	if err != nil {
+		invokeFailure(c, err)
+		return
+	}
+
+	success(c, gin.H{"tx_id": resp.TransactionID})
+}
+
+// GetPrivate 查询私有数据
+// GET /cc/example_cc/private/:collection/:key
+func (ctl *ExampleCC) GetPrivate(c *gin.Context) {
+	collection, key := c.Param("collection"), c.Param("key")
+
+	resp, err := ctl.Setup.QueryAsContext(c.Request.Context(), middleware.GetOrg(c), middleware.GetEnrollmentID(c), channel.Request{ChaincodeID: ctl.Setup.ChainCode.ID, Fcn: "getPrivate", Args: blockchain.GetParams([]string{collection, key})})
+	if err != nil {
+		invokeFailure(c, err)
+		return
+	}
+
+	success(c, gin.H{"collection": collection, "key": key, "value": string(resp.Payload)})
+}
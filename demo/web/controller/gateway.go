@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"encoding/base64"
+	"net/http"
+
+	"bcfish.cn/demo/web/blockchain"
+	"bcfish.cn/demo/web/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+	"github.com/pkg/errors"
+)
+
+const (
+	encodingString = "string"
+	encodingBase64 = "base64"
+)
+
+// Gateway 通用链码网关
+type Gateway struct {
+	Setup *blockchain.FabricSetup
+}
+
+// GatewayRequest 通用链码调用请求
+type GatewayRequest struct {
+	Fcn  string   `json:"fcn" binding:"required"`
+	Args []string `json:"args"`
+	// Encoding args 与 transient 的编码方式, string(默认) 或 base64
+	Encoding  string            `json:"encoding"`
+	Transient map[string]string `json:"transient"`
+}
+
+// toChannelRequest 按编码方式解析参数
+func (req *GatewayRequest) toChannelRequest(chaincodeID string) (channel.Request, error) {
+	request := channel.Request{ChaincodeID: chaincodeID, Fcn: req.Fcn}
+
+	for _, arg := range req.Args {
+		value, err := decodeValue(req.Encoding, arg)
+		if err != nil {
+			return request, errors.WithMessage(err, "invalid args")
+		}
+		request.Args = append(request.Args, value)
+	}
+
+	if len(req.Transient) > 0 {
+		request.TransientMap = make(map[string][]byte, len(req.Transient))
+		for key, item := range req.Transient {
+			value, err := decodeValue(req.Encoding, item)
+			if err != nil {
+				return request, errors.WithMessage(err, "invalid transient data "+key)
+			}
+			request.TransientMap[key] = value
+		}
+	}
+
+	return request, nil
+}
+
+func decodeValue(encoding, value string) ([]byte, error) {
+	switch encoding {
+	case "", encodingString:
+		return []byte(value), nil
+	case encodingBase64:
+		return base64.StdEncoding.DecodeString(value)
+	}
+	return nil, errors.Errorf("unsupported encoding %s", encoding)
+}
+
+// Invoke 提交交易
+// POST /channels/:channel/chaincodes/:cc/invoke
+func (ctl *Gateway) Invoke(c *gin.Context) {
+	request, ok := ctl.bind(c)
+	if !ok {
+		return
+	}
+
+	resp, err := ctl.Setup.ExecuteOnChannelContext(c.Request.Context(), c.Param("channel"), middleware.GetOrg(c), middleware.GetEnrollmentID(c), request)
+	if err != nil {
+		invokeFailure(c, err)
+		return
+	}
+
+	success(c, blockchain.NewTxResult(resp))
+}
+
+// Query 查询链码
+// POST /channels/:channel/chaincodes/:cc/query
+func (ctl *Gateway) Query(c *gin.Context) {
+	request, ok := ctl.bind(c)
+	if !ok {
+		return
+	}
+
+	resp, err := ctl.Setup.QueryOnChannelContext(c.Request.Context(), c.Param("channel"), middleware.GetOrg(c), middleware.GetEnrollmentID(c), request)
+	if err != nil {
+		invokeFailure(c, err)
+		return
+	}
+
+	success(c, blockchain.NewTxResult(resp))
+}
+
+func (ctl *Gateway) bind(c *gin.Context) (channel.Request, bool) {
+	var req GatewayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return channel.Request{}, false
+	}
+
+	request, err := req.toChannelRequest(c.Param("cc"))
+	if err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return channel.Request{}, false
+	}
+	return request, true
+}
@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"net/http"
+
+	"bcfish.cn/demo/web/blockchain"
+	"github.com/gin-gonic/gin"
+	mspclient "github.com/hyperledger/fabric-sdk-go/pkg/client/msp"
+)
+
+// Identity CA用户管理接口
+type Identity struct {
+	Setup *blockchain.FabricSetup
+}
+
+// Attribute 用户属性
+type Attribute struct {
+	Name  string `json:"name" binding:"required"`
+	Value string `json:"value"`
+	// ECert 是否写入登记证书
+	ECert bool `json:"ecert"`
+}
+
+// RegisterRequest 注册请求, secret 为空时由CA生成
+type RegisterRequest struct {
+	Name           string      `json:"name" binding:"required"`
+	Secret         string      `json:"secret"`
+	Type           string      `json:"type"`
+	Affiliation    string      `json:"affiliation"`
+	MaxEnrollments int         `json:"max_enrollments"`
+	Attributes     []Attribute `json:"attributes"`
+}
+
+// EnrollRequest 登记请求
+type EnrollRequest struct {
+	Secret string `json:"secret" binding:"required"`
+}
+
+// Register 注册用户
+// POST /identities
+func (ctl *Identity) Register(c *gin.Context) {
+	var req RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+
+	request := &mspclient.RegistrationRequest{
+		Name:           req.Name,
+		Secret:         req.Secret,
+		Type:           req.Type,
+		Affiliation:    req.Affiliation,
+		MaxEnrollments: req.MaxEnrollments,
+	}
+	for _, attr := range req.Attributes {
+		request.Attributes = append(request.Attributes, mspclient.Attribute{Name: attr.Name, Value: attr.Value, ECert: attr.ECert})
+	}
+
+	secret, err := ctl.Setup.Register(request)
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, gin.H{"name": req.Name, "secret": secret})
+}
+
+// Enroll 登记用户
+// POST /identities/:id/enroll
+func (ctl *Identity) Enroll(c *gin.Context) {
+	var req EnrollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := ctl.Setup.Enroll(c.Param("id"), req.Secret); err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, nil)
+}
+
+// Reenroll 重新登记用户
+// POST /identities/:id/reenroll
+func (ctl *Identity) Reenroll(c *gin.Context) {
+	if err := ctl.Setup.Reenroll(c.Param("id")); err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, nil)
+}
+
+// Revoke 吊销用户
+// DELETE /identities/:id?reason=
+func (ctl *Identity) Revoke(c *gin.Context) {
+	resp, err := ctl.Setup.Revoke(c.Param("id"), c.Query("reason"))
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, resp)
+}
+
+// List 用户列表
+// GET /identities
+func (ctl *Identity) List(c *gin.Context) {
+	identities, err := ctl.Setup.Identities()
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, identities)
+}
+
+// Get 查询用户
+// GET /identities/:id
+func (ctl *Identity) Get(c *gin.Context) {
+	identity, err := ctl.Setup.Identity(c.Param("id"))
+	if err != nil {
+		failure(c, http.StatusNotFound, err)
+		return
+	}
+	success(c, identity)
+}
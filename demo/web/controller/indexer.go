@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"bcfish.cn/demo/web/blockchain"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// Indexer 本地区块索引查询接口, 只包含默认通道的交易
+type Indexer struct {
+	Indexer *blockchain.Indexer
+}
+
+// Height 本地已同步高度
+// GET /index/height
+func (ctl *Indexer) Height(c *gin.Context) {
+	height, err := ctl.Indexer.Height()
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, gin.H{"height": height})
+}
+
+// TransactionsByKey 写入过指定key的有效交易
+// GET /index/chaincodes/:cc/keys/:key/transactions?limit=
+func (ctl *Indexer) TransactionsByKey(c *gin.Context) {
+	limit, err := queryLimit(c)
+	if err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+
+	txs, err := ctl.Indexer.TransactionsByKey(c.Param("cc"), c.Param("key"), limit)
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, txs)
+}
+
+// TransactionsByCreator 指定MSP提交的交易
+// GET /index/creators/:msp/transactions?limit=
+func (ctl *Indexer) TransactionsByCreator(c *gin.Context) {
+	limit, err := queryLimit(c)
+	if err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+
+	txs, err := ctl.Indexer.TransactionsByCreator(c.Param("msp"), limit)
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, txs)
+}
+
+// TransactionsByTime 时间范围内的交易, from/to 为 RFC3339 格式, to 默认为当前时间
+// GET /index/transactions?from=&to=&limit=
+func (ctl *Indexer) TransactionsByTime(c *gin.Context) {
+	limit, err := queryLimit(c)
+	if err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+
+	from, err := time.Parse(time.RFC3339, c.Query("from"))
+	if err != nil {
+		failure(c, http.StatusBadRequest, errors.Wrap(err, "invalid from"))
+		return
+	}
+	to := time.Now()
+	if value := c.Query("to"); value != "" {
+		if to, err = time.Parse(time.RFC3339, value); err != nil {
+			failure(c, http.StatusBadRequest, errors.Wrap(err, "invalid to"))
+			return
+		}
+	}
+
+	txs, err := ctl.Indexer.TransactionsByTime(from, to, limit)
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, txs)
+}
+
+// queryLimit 解析 limit 参数, 默认100
+func queryLimit(c *gin.Context) (int, error) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 0 {
+		return 0, errors.New("invalid limit")
+	}
+	return limit, nil
+}
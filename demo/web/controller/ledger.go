@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"bcfish.cn/demo/web/blockchain"
+	"github.com/gin-gonic/gin"
+)
+
+// Ledger 区块浏览接口
+type Ledger struct {
+	Setup *blockchain.FabricSetup
+}
+
+// ledger 返回请求路径中 :channel 对应通道的账本, 未指定时为默认通道
+func (ctl *Ledger) ledger(c *gin.Context) (*blockchain.ChannelLedger, bool) {
+	channelID := c.Param("channel")
+	if channelID == "" {
+		channelID = ctl.Setup.ChannelConfig.ID
+	}
+
+	l, err := ctl.Setup.Ledger(channelID)
+	if err != nil {
+		failure(c, http.StatusNotFound, err)
+		return nil, false
+	}
+	return l.WithContext(c.Request.Context()), true
+}
+
+// Info 账本信息
+// GET /ledger/info
+// GET /channels/:channel/ledger/info
+func (ctl *Ledger) Info(c *gin.Context) {
+	l, ok := ctl.ledger(c)
+	if !ok {
+		return
+	}
+
+	info, err := l.QueryInfo()
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, info)
+}
+
+// Block 按区块号查询区块
+// GET /ledger/blocks/:number
+// GET /channels/:channel/ledger/blocks/:number
+func (ctl *Ledger) Block(c *gin.Context) {
+	number, err := strconv.ParseUint(c.Param("number"), 10, 64)
+	if err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+
+	l, ok := ctl.ledger(c)
+	if !ok {
+		return
+	}
+
+	block, err := l.QueryBlock(number)
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, block)
+}
+
+// BlockByHash 按区块哈希查询区块
+// GET /ledger/blockhash/:hash
+// GET /channels/:channel/ledger/blockhash/:hash
+func (ctl *Ledger) BlockByHash(c *gin.Context) {
+	l, ok := ctl.ledger(c)
+	if !ok {
+		return
+	}
+
+	block, err := l.QueryBlockByHash(c.Param("hash"))
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, block)
+}
+
+// BlockByTxID 查询交易所在区块
+// GET /ledger/transactions/:txid/block
+// GET /channels/:channel/ledger/transactions/:txid/block
+func (ctl *Ledger) BlockByTxID(c *gin.Context) {
+	l, ok := ctl.ledger(c)
+	if !ok {
+		return
+	}
+
+	block, err := l.QueryBlockByTxID(c.Param("txid"))
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, block)
+}
+
+// Transaction 按交易ID查询交易
+// GET /ledger/transactions/:txid
+// GET /channels/:channel/ledger/transactions/:txid
+func (ctl *Ledger) Transaction(c *gin.Context) {
+	l, ok := ctl.ledger(c)
+	if !ok {
+		return
+	}
+
+	tx, err := l.QueryTransaction(c.Param("txid"))
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, tx)
+}
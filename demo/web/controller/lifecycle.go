@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"bcfish.cn/demo/web/blockchain"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// Lifecycle 链码生命周期管理接口
+type Lifecycle struct {
+	Setup *blockchain.FabricSetup
+}
+
+// ChainCodeRequest 链码信息, path 与 collections_file 为已配置链码 GOPATH 下 src 目录中的相对路径
+type ChainCodeRequest struct {
+	Name            string `json:"name" binding:"required"`
+	Version         string `json:"version" binding:"required"`
+	Path            string `json:"path" binding:"required"`
+	Policy          string `json:"policy"`
+	CollectionsFile string `json:"collections_file"`
+}
+
+// InstallRequest 安装请求, orgs/peers 为空时安装到所有组织的所有peer
+type InstallRequest struct {
+	ChainCodeRequest
+	Orgs  []string `json:"orgs"`
+	Peers []string `json:"peers"`
+}
+
+// DeployRequest 实例化或升级请求, args 为空时以 "init" 初始化
+type DeployRequest struct {
+	ChainCodeRequest
+	Args []string `json:"args"`
+}
+
+func (ctl *Lifecycle) chainCode(req ChainCodeRequest) (blockchain.ChainCode, error) {
+	chainCode := blockchain.ChainCode{ID: req.Name, Version: req.Version, GoPath: ctl.Setup.ChainCode.GoPath, Policy: req.Policy}
+
+	srcPath, err := relativePath(req.Path)
+	if err != nil {
+		return chainCode, err
+	}
+	chainCode.SrcPath = srcPath
+
+	if req.CollectionsFile != "" {
+		collectionsFile, err := relativePath(req.CollectionsFile)
+		if err != nil {
+			return chainCode, err
+		}
+		chainCode.CollectionsFile = filepath.Join(chainCode.GoPath, "src", collectionsFile)
+	}
+	return chainCode, nil
+}
+
+// relativePath 校验请求中的路径, 不允许绝对路径与 "..", 防止访问 GOPATH/src 之外的文件
+func relativePath(path string) (string, error) {
+	if filepath.IsAbs(path) || strings.HasPrefix(path, "/") {
+		return "", errors.Errorf("path %s must be relative", path)
+	}
+	for _, element := range strings.Split(filepath.ToSlash(path), "/") {
+		if element == ".." {
+			return "", errors.Errorf("path %s must not contain ..", path)
+		}
+	}
+	return path, nil
+}
+
+func (req *DeployRequest) args() [][]byte {
+	if len(req.Args) == 0 {
+		return [][]byte{[]byte("init")}
+	}
+	return blockchain.GetParams(req.Args)
+}
+
+// Install 安装链码, 返回每个peer的安装结果
+// POST /lifecycle/install
+func (ctl *Lifecycle) Install(c *gin.Context) {
+	var req InstallRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+	chainCode, err := ctl.chainCode(req.ChainCodeRequest)
+	if err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+
+	results, err := ctl.Setup.InstallChainCodeContext(c.Request.Context(), chainCode, req.Orgs, req.Peers)
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, results)
+}
+
+// Installed 各peer已安装的链码
+// GET /lifecycle/installed
+func (ctl *Lifecycle) Installed(c *gin.Context) {
+	result, err := ctl.Setup.InstalledChainCodesContext(c.Request.Context())
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, result)
+}
+
+// Instantiated 通道上已实例化的链码
+// GET /channels/:channel/lifecycle/chaincodes
+func (ctl *Lifecycle) Instantiated(c *gin.Context) {
+	result, err := ctl.Setup.InstantiatedChainCodesContext(c.Request.Context(), c.Param("channel"))
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, result)
+}
+
+// Instantiate 在通道上实例化链码
+// POST /channels/:channel/lifecycle/instantiate
+func (ctl *Lifecycle) Instantiate(c *gin.Context) {
+	var req DeployRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+	chainCode, err := ctl.chainCode(req.ChainCodeRequest)
+	if err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+
+	txID, err := ctl.Setup.InstantiateChainCodeContext(c.Request.Context(), c.Param("channel"), chainCode, req.args())
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, gin.H{"tx_id": txID})
+}
+
+// Upgrade 将通道上的链码升级到新版本
+// POST /channels/:channel/lifecycle/upgrade
+func (ctl *Lifecycle) Upgrade(c *gin.Context) {
+	var req DeployRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+	chainCode, err := ctl.chainCode(req.ChainCodeRequest)
+	if err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+
+	txID, err := ctl.Setup.UpgradeChainCodeContext(c.Request.Context(), c.Param("channel"), chainCode, req.args())
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, gin.H{"tx_id": txID})
+}
@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"net/http"
+
+	"bcfish.cn/demo/web/blockchain"
+	"github.com/gin-gonic/gin"
+)
+
+// success 返回成功结果
+func success(c *gin.Context, data interface{}) {
+	c.JSON(http.StatusOK, blockchain.Msg{
+		StatusCode: http.StatusOK,
+		Message:    "success",
+		Data:       data,
+	})
+}
+
+// failure 返回失败结果, statusCode 同时作为http状态码, 错误记录在请求日志中
+func failure(c *gin.Context, statusCode int, err error) {
+	c.Error(err)
+	c.JSON(statusCode, blockchain.Msg{
+		StatusCode: statusCode,
+		Message:    err.Error(),
+	})
+}
+
+// invokeFailure 返回链码调用失败结果, 链码业务错误按错误码返回 400/404/409, 其他错误返回 500
+func invokeFailure(c *gin.Context, err error) {
+	ccErr, ok := blockchain.AsChainCodeError(err)
+	if !ok {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.Error(err)
+	c.JSON(ccErr.HTTPStatus(), blockchain.Msg{
+		StatusCode: ccErr.HTTPStatus(),
+		Code:       ccErr.Code,
+		Message:    ccErr.Message,
+	})
+}
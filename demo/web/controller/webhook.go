@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"net/http"
+
+	"bcfish.cn/demo/web/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+// Webhook webhook订阅管理接口
+type Webhook struct {
+	Dispatcher *webhook.Dispatcher
+}
+
+// SubscribeRequest 订阅请求
+type SubscribeRequest struct {
+	URL         string `json:"url" binding:"required"`
+	EventType   string `json:"event_type" binding:"required"`
+	ChaincodeID string `json:"chaincode_id"`
+	EventFilter string `json:"event_filter"`
+	Secret      string `json:"secret"`
+}
+
+// Subscribe 注册订阅
+// POST /webhooks
+func (ctl *Webhook) Subscribe(c *gin.Context) {
+	var req SubscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+
+	sub, err := ctl.Dispatcher.Subscribe(&webhook.Subscription{
+		URL:         req.URL,
+		EventType:   req.EventType,
+		ChaincodeID: req.ChaincodeID,
+		EventFilter: req.EventFilter,
+		Secret:      req.Secret,
+	})
+	if err != nil {
+		failure(c, http.StatusBadRequest, err)
+		return
+	}
+	success(c, hideSecret(*sub))
+}
+
+// List 订阅列表
+// GET /webhooks
+func (ctl *Webhook) List(c *gin.Context) {
+	subs, err := ctl.Dispatcher.Subscriptions()
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	result := make([]webhook.Subscription, 0, len(subs))
+	for _, sub := range subs {
+		result = append(result, hideSecret(*sub))
+	}
+	success(c, result)
+}
+
+// Unsubscribe 删除订阅
+// DELETE /webhooks/:id
+func (ctl *Webhook) Unsubscribe(c *gin.Context) {
+	if err := ctl.Dispatcher.Unsubscribe(c.Param("id")); err != nil {
+		failure(c, http.StatusNotFound, err)
+		return
+	}
+	success(c, nil)
+}
+
+// DeadLetters 投递失败的事件
+// GET /webhooks/:id/dead-letters
+func (ctl *Webhook) DeadLetters(c *gin.Context) {
+	letters, err := ctl.Dispatcher.DeadLetters(c.Param("id"))
+	if err != nil {
+		failure(c, http.StatusInternalServerError, err)
+		return
+	}
+	success(c, letters)
+}
+
+// hideSecret 返回结果中不包含签名密钥
+func hideSecret(sub webhook.Subscription) webhook.Subscription {
+	sub.Secret = ""
+	return sub
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"bcfish.cn/demo/web/blockchain"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+const (
+	// EnrollmentIDKey gin上下文中当前请求用户的登记ID
+	EnrollmentIDKey = "enrollment_id"
+	// OrgKey gin上下文中当前请求用户的组织
+	OrgKey = "org"
+	// RolesKey gin上下文中当前请求用户的角色
+	RolesKey = "roles"
+
+	// RoleMember 组织成员
+	RoleMember = "member"
+	// RoleAdmin 组织管理员, 可以管理通道, 链码与用户
+	RoleAdmin = "admin"
+
+	tokenExpiration = 12 * time.Hour
+)
+
+var jwtSecret = loadJWTSecret()
+
+// Claims JWT中携带的用户信息
+type Claims struct {
+	EnrollmentID string   `json:"enrollment_id"`
+	Org          string   `json:"org"`
+	Roles        []string `json:"roles"`
+	jwt.StandardClaims
+}
+
+// loadJWTSecret 读取环境变量 JWT_SECRET, 未设置时随机生成, 重启后之前签发的token失效
+func loadJWTSecret() []byte {
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	Logger.Warn("JWT_SECRET is not set, using a random secret")
+	return secret
+}
+
+// GenerateToken 为登记用户签发token
+func GenerateToken(enrollmentID, org string, roles []string) (string, error) {
+	claims := Claims{
+		EnrollmentID: enrollmentID,
+		Org:          org,
+		Roles:        roles,
+		StandardClaims: jwt.StandardClaims{
+			Subject:   enrollmentID,
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: time.Now().Add(tokenExpiration).Unix(),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+}
+
+// ParseToken 校验token并返回其中的用户信息
+func ParseToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return jwtSecret, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// Auth 校验 Authorization: Bearer <token>, 并注入链码调用使用的用户身份
+//
+// 浏览器的 EventSource 与 WebSocket 无法设置请求头, 此时可以使用 access_token 查询参数
+func Auth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString := c.Query("access_token")
+		if header := c.GetHeader("Authorization"); strings.HasPrefix(header, "Bearer ") {
+			tokenString = strings.TrimPrefix(header, "Bearer ")
+		}
+		if tokenString == "" {
+			abort(c, http.StatusUnauthorized, errors.New("missing bearer token"))
+			return
+		}
+
+		claims, err := ParseToken(tokenString)
+		if err != nil {
+			abort(c, http.StatusUnauthorized, err)
+			return
+		}
+
+		c.Set(EnrollmentIDKey, claims.EnrollmentID)
+		c.Set(OrgKey, claims.Org)
+		c.Set(RolesKey, claims.Roles)
+		c.Next()
+	}
+}
+
+// RequireRole 要求当前用户拥有指定角色, 必须在 Auth 之后使用
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, item := range c.GetStringSlice(RolesKey) {
+			if item == role {
+				c.Next()
+				return
+			}
+		}
+		abort(c, http.StatusForbidden, errors.Errorf("role %s is required", role))
+	}
+}
+
+// GetEnrollmentID 返回当前请求用户的登记ID, 为空时使用默认用户
+func GetEnrollmentID(c *gin.Context) string {
+	return c.GetString(EnrollmentIDKey)
+}
+
+// GetOrg 返回当前请求用户所属的组织, 为空时使用默认组织
+func GetOrg(c *gin.Context) string {
+	return c.GetString(OrgKey)
+}
+
+func abort(c *gin.Context, statusCode int, err error) {
+	c.Error(err)
+	c.AbortWithStatusJSON(statusCode, blockchain.Msg{
+		StatusCode: statusCode,
+		Message:    err.Error(),
+	})
+}
@@ -10,37 +10,59 @@ var (
 )
 
 // GetFabricSetupInstance 获取fabric初始化的实例
-func GetFabricSetupInstance() *blockchain.FabricSetup {
-	fabricSetup := blockchain.FabricSetup {
-		ConfigFile: "config.yaml",
-
-		Org: blockchain.Org{
-			ID: "org1.example.com",
-			Name: "org1",
-			Admin: "Admin",
-			User: "User1",
+func GetFabricSetupInstance() (*blockchain.FabricSetup, error) {
+	orgs := []blockchain.Org{
+		{
+			ID:      "org1.example.com",
+			MspID:   "Org1MSP",
+			Name:    "org1",
+			Admin:   "Admin",
+			User:    "User1",
 			OrderID: "orderer.example.com",
 		},
-		ChannelConfig:blockchain.ChannelConfig{
-			ID: "mychannel",
-			FilePath: goPath + "/src/bcfish.cn/demo/artifacts/channel/mychannel.tx",
+		{
+			ID:      "org2.example.com",
+			MspID:   "Org2MSP",
+			Name:    "org2",
+			Admin:   "Admin",
+			User:    "User1",
+			OrderID: "orderer.example.com",
+		},
+	}
+	channelConfig := blockchain.ChannelConfig{
+		ID:       "mychannel",
+		FilePath: goPath + "/src/bcfish.cn/demo/artifacts/channel/mychannel.tx",
+		AnchorPeers: map[string][]string{
+			"org1": {"peer0.org1.example.com:7051"},
+			"org2": {"peer0.org2.example.com:7051"},
 		},
+	}
 
+	setup, err := blockchain.NewFabricSetup("config.yaml", orgs, channelConfig)
+	if err != nil {
+		return nil, err
 	}
+	setup.OrdererOrg = blockchain.Org{
+		ID:      "example.com",
+		MspID:   "OrdererMSP",
+		Name:    "ordererorg",
+		Admin:   "Admin",
+		OrderID: "orderer.example.com",
+	}
+	setup.Logger = Logger
 
-	return &fabricSetup
+	return setup, nil
 }
 
 // InitExampleCC 初始化 example链码
-func InitExampleCC(fabricSetup *blockchain.FabricSetup) (*blockchain.FabricSetup, error) {
-	temp := &fabricSetup
-
-	setup := *temp
+func InitExampleCC(setup *blockchain.FabricSetup) (*blockchain.FabricSetup, error) {
 	setup.ChainCode = blockchain.ChainCode{
-		ID: "example_cc",
-		Version: "0.1",
-		GoPath: goPath,
-		SrcPath: "bcfish.cn/demo/artifacts/src/go/",
+		ID:              "example_cc",
+		Version:         "1.2",
+		GoPath:          goPath,
+		SrcPath:         "bcfish.cn/demo/artifacts/src/go/",
+		Policy:          "OR('Org1MSP.member','Org2MSP.member')",
+		CollectionsFile: goPath + "/src/bcfish.cn/demo/artifacts/collections_config.json",
 	}
 
 	if err := setup.InstallAndInstantiateCC();err != nil {
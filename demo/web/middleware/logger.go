@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// RequestIDKey gin上下文中的请求ID
+	RequestIDKey = "request_id"
+	// HeaderRequestID 请求ID请求头, 客户端未提供时生成
+	HeaderRequestID = "X-Request-ID"
+)
+
+// Logger middleware 包及请求日志使用的日志, 启动时可替换为与 FabricSetup 相同的日志
+var Logger logrus.FieldLogger = logrus.StandardLogger()
+
+// RequestLogger 为每个请求分配请求ID并在请求结束后记录日志, 代替 gin.Logger
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		requestID := c.GetHeader(HeaderRequestID)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		c.Set(RequestIDKey, requestID)
+		c.Header(HeaderRequestID, requestID)
+
+		c.Next()
+
+		fields := logrus.Fields{
+			RequestIDKey: requestID,
+			"method":     c.Request.Method,
+			"path":       c.Request.URL.Path,
+			"status":     c.Writer.Status(),
+			"latency":    time.Since(start).String(),
+			"client_ip":  c.ClientIP(),
+		}
+		if id := GetEnrollmentID(c); id != "" {
+			fields[EnrollmentIDKey] = id
+		}
+		entry := Logger.WithFields(fields)
+		if len(c.Errors) > 0 {
+			entry = entry.WithField("error", c.Errors.String())
+		}
+
+		switch status := c.Writer.Status(); {
+		case status >= 500:
+			entry.Error("request failed")
+		case status >= 400:
+			entry.Warn("request rejected")
+		default:
+			entry.Info("request completed")
+		}
+	}
+}
+
+// GetLogger 返回带有请求ID的日志
+func GetLogger(c *gin.Context) logrus.FieldLogger {
+	return Logger.WithField(RequestIDKey, c.GetString(RequestIDKey))
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
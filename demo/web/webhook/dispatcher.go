@@ -0,0 +1,462 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"bcfish.cn/demo/web/blockchain"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// 投递请求头
+const (
+	HeaderSubscription = "X-Webhook-Subscription"
+	HeaderEvent        = "X-Webhook-Event"
+	HeaderSignature    = "X-Webhook-Signature"
+)
+
+// BlockSource 区块来源, blockchain.FabricSetup 实现了该接口
+type BlockSource interface {
+	QueryInfo() (*blockchain.BlockchainInfo, error)
+	QueryBlock(number uint64) (*blockchain.Block, error)
+}
+
+// Config 投递配置, 零值字段使用默认值
+type Config struct {
+	// MaxAttempts 每个事件的最大投递次数
+	MaxAttempts int
+	// InitialBackoff 第一次重试前的等待时间, 之后每次翻倍
+	InitialBackoff time.Duration
+	// MaxBackoff 重试等待时间上限
+	MaxBackoff time.Duration
+	// PollInterval 没有新区块通知时检查账本高度的间隔
+	PollInterval time.Duration
+	Client       *http.Client
+	// Logger 为空时使用 logrus 默认日志
+	Logger logrus.FieldLogger
+}
+
+func (config *Config) setDefaults() {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = time.Second
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = time.Minute
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = 10 * time.Second
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if config.Logger == nil {
+		config.Logger = logrus.StandardLogger()
+	}
+}
+
+// Dispatcher 将链码事件与区块事件以 webhook 形式推送给订阅者
+//
+// 每个订阅由独立的协程按区块号顺序投递, 每处理完一个区块保存一次检查点,
+// 重启后从检查点继续, 因此不会遗漏区块. 重试耗尽的事件写入死信列表后继续投递后续事件
+type Dispatcher struct {
+	source BlockSource
+	store  *store
+	config Config
+
+	// height 已知的账本高度
+	height uint64
+
+	mu      sync.Mutex
+	workers map[string]*worker
+
+	events  *blockchain.Subscription
+	started bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+type worker struct {
+	sub    *Subscription
+	filter *regexp.Regexp
+	notify chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewDispatcher 打开订阅存储并为已有订阅启动投递协程
+func NewDispatcher(source BlockSource, path string, config Config) (*Dispatcher, error) {
+	config.setDefaults()
+
+	s, err := openStore(path)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Dispatcher{
+		source:  source,
+		store:   s,
+		config:  config,
+		workers: make(map[string]*worker),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	subs, err := s.subscriptions()
+	if err != nil {
+		s.close()
+		return nil, errors.Wrap(err, "failed to load webhook subscriptions")
+	}
+	if err := d.refreshHeight(); err != nil {
+		s.close()
+		return nil, err
+	}
+	for _, sub := range subs {
+		if err := d.startWorker(sub); err != nil {
+			s.close()
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Start 通过 hub 接收新区块通知, hub 为 nil 时只按 PollInterval 轮询账本高度
+func (d *Dispatcher) Start(hub *blockchain.EventHub) error {
+	var blocks <-chan *blockchain.Event
+	if hub != nil {
+		events, err := hub.SubscribeFilteredBlocks()
+		if err != nil {
+			return err
+		}
+		d.events = events
+		blocks = events.C
+	}
+
+	d.started = true
+	go d.poll(blocks)
+	return nil
+}
+
+// Close 停止所有投递协程并关闭存储
+func (d *Dispatcher) Close() error {
+	close(d.stop)
+	if d.events != nil {
+		d.events.Close()
+	}
+	if d.started {
+		<-d.done
+	}
+
+	d.mu.Lock()
+	workers := d.workers
+	d.workers = make(map[string]*worker)
+	d.mu.Unlock()
+
+	for _, w := range workers {
+		close(w.stop)
+		<-w.done
+	}
+	return d.store.close()
+}
+
+// Subscribe 注册订阅, 从当前账本高度开始投递
+func (d *Dispatcher) Subscribe(sub *Subscription) (*Subscription, error) {
+	switch sub.EventType {
+	case blockchain.EventChaincode:
+		if sub.ChaincodeID == "" {
+			return nil, errors.New("chaincode_id is required for chaincode events")
+		}
+	case blockchain.EventBlock:
+	default:
+		return nil, errors.Errorf("unsupported event type %s", sub.EventType)
+	}
+	if _, err := regexp.Compile(sub.EventFilter); err != nil {
+		return nil, errors.Wrap(err, "invalid event filter")
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	sub.ID = id
+	sub.Checkpoint = atomic.LoadUint64(&d.height)
+	sub.CreatedAt = time.Now()
+
+	if err := d.store.saveSubscription(sub); err != nil {
+		return nil, errors.Wrap(err, "failed to save subscription")
+	}
+	if err := d.startWorker(sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Unsubscribe 删除订阅及其死信
+func (d *Dispatcher) Unsubscribe(id string) error {
+	d.mu.Lock()
+	w, ok := d.workers[id]
+	delete(d.workers, id)
+	d.mu.Unlock()
+
+	if ok {
+		close(w.stop)
+		<-w.done
+	}
+	return d.store.deleteSubscription(id)
+}
+
+// Subscriptions 返回所有订阅
+func (d *Dispatcher) Subscriptions() ([]*Subscription, error) {
+	return d.store.subscriptions()
+}
+
+// DeadLetters 返回订阅的死信列表
+func (d *Dispatcher) DeadLetters(id string) ([]*DeadLetter, error) {
+	return d.store.deadLetters(id)
+}
+
+func (d *Dispatcher) startWorker(sub *Subscription) error {
+	filter, err := regexp.Compile(sub.EventFilter)
+	if err != nil {
+		return errors.Wrapf(err, "invalid event filter of subscription %s", sub.ID)
+	}
+
+	// The worker advances its own copy, the caller keeps reading the one it passed in
+	copied := *sub
+	w := &worker{
+		sub:    &copied,
+		filter: filter,
+		notify: make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	d.mu.Lock()
+	d.workers[sub.ID] = w
+	d.mu.Unlock()
+
+	go d.runWorker(w)
+	return nil
+}
+
+// poll 根据区块通知或定时器更新账本高度并唤醒投递协程
+func (d *Dispatcher) poll(blocks <-chan *blockchain.Event) {
+	defer close(d.done)
+
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e, ok := <-blocks:
+			if !ok {
+				blocks = nil
+				continue
+			}
+			d.advanceHeight(e.BlockNumber + 1)
+		case <-ticker.C:
+			if err := d.refreshHeight(); err != nil {
+				d.config.Logger.WithError(err).Warn("webhook: failed to refresh ledger height")
+			}
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) refreshHeight() error {
+	info, err := d.source.QueryInfo()
+	if err != nil {
+		return errors.WithMessage(err, "failed to query ledger height")
+	}
+	d.advanceHeight(info.Height)
+	return nil
+}
+
+func (d *Dispatcher) advanceHeight(height uint64) {
+	for {
+		current := atomic.LoadUint64(&d.height)
+		if height <= current {
+			return
+		}
+		if atomic.CompareAndSwapUint64(&d.height, current, height) {
+			break
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, w := range d.workers {
+		select {
+		case w.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// runWorker 在新区块通知时投递, 查询区块失败时按指数退避重试, 不必等待下一个区块
+func (d *Dispatcher) runWorker(w *worker) {
+	defer close(w.done)
+
+	backoff := d.config.InitialBackoff
+	for {
+		var retry <-chan time.Time
+		if err := d.catchUp(w); err != nil {
+			d.config.Logger.WithError(err).WithField("subscription", w.sub.ID).Warn("webhook: delivery interrupted, retry in ", backoff)
+			retry = time.After(backoff)
+			backoff *= 2
+			if backoff > d.config.MaxBackoff {
+				backoff = d.config.MaxBackoff
+			}
+		} else {
+			backoff = d.config.InitialBackoff
+		}
+
+		select {
+		case <-w.notify:
+		case <-retry:
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// catchUp 按顺序投递检查点到已知高度之间的区块
+func (d *Dispatcher) catchUp(w *worker) error {
+	for w.sub.Checkpoint < atomic.LoadUint64(&d.height) {
+		block, err := d.source.QueryBlock(w.sub.Checkpoint)
+		if err != nil {
+			return errors.WithMessage(err, "failed to query block")
+		}
+
+		for _, e := range w.events(block) {
+			if !d.deliverWithRetry(w, e) {
+				// 停止时不推进检查点, 重启后重新投递该区块
+				return nil
+			}
+		}
+
+		if err := d.store.saveCheckpoint(w.sub.ID, block.Number+1); err != nil {
+			return errors.Wrap(err, "failed to save checkpoint")
+		}
+		w.sub.Checkpoint = block.Number + 1
+	}
+	return nil
+}
+
+// events 返回区块中与订阅匹配的事件
+func (w *worker) events(block *blockchain.Block) []*blockchain.Event {
+	if w.sub.EventType == blockchain.EventBlock {
+		return []*blockchain.Event{{Type: blockchain.EventBlock, BlockNumber: block.Number, Block: block}}
+	}
+
+	var events []*blockchain.Event
+	for _, tx := range block.Transactions {
+		if tx.Event == nil || tx.ValidationCode != "VALID" {
+			continue
+		}
+		if tx.Event.ChaincodeID != w.sub.ChaincodeID || !w.filter.MatchString(tx.Event.EventName) {
+			continue
+		}
+		events = append(events, &blockchain.Event{
+			Type:        blockchain.EventChaincode,
+			BlockNumber: block.Number,
+			TxID:        tx.TxID,
+			ChaincodeID: tx.Event.ChaincodeID,
+			EventName:   tx.Event.EventName,
+			Payload:     tx.Event.Payload,
+		})
+	}
+	return events
+}
+
+// deliverWithRetry 按指数退避重试投递, 重试耗尽后写入死信. 协程被停止时返回 false
+func (d *Dispatcher) deliverWithRetry(w *worker, e *blockchain.Event) bool {
+	backoff := d.config.InitialBackoff
+
+	var err error
+	for attempt := 1; attempt <= d.config.MaxAttempts; attempt++ {
+		if err = d.deliver(w.sub, e); err == nil {
+			return true
+		}
+		if attempt == d.config.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-w.stop:
+			return false
+		}
+		backoff *= 2
+		if backoff > d.config.MaxBackoff {
+			backoff = d.config.MaxBackoff
+		}
+	}
+
+	letter := &DeadLetter{SubscriptionID: w.sub.ID, Event: e, Attempts: d.config.MaxAttempts, Error: err.Error(), FailedAt: time.Now()}
+	if err := d.store.addDeadLetter(letter); err != nil {
+		d.config.Logger.WithError(err).WithField("subscription", w.sub.ID).Error("webhook: failed to save dead letter")
+	}
+	return true
+}
+
+// deliver 投递一次事件, 非 2xx 响应视为失败
+func (d *Dispatcher) deliver(sub *Subscription, e *blockchain.Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderSubscription, sub.ID)
+	req.Header.Set(HeaderEvent, e.Type)
+	if sub.Secret != "" {
+		req.Header.Set(HeaderSignature, "sha256="+Sign(sub.Secret, body))
+	}
+
+	resp, err := d.config.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign 计算请求体的 HMAC-SHA256 签名(十六进制), 接收方可用于校验 X-Webhook-Signature
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "failed to generate id")
+	}
+	return hex.EncodeToString(b), nil
+}
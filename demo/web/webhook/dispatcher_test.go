@@ -0,0 +1,340 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"bcfish.cn/demo/web/blockchain"
+	"github.com/pkg/errors"
+)
+
+// fakeSource 内存中的账本, 高度以内的每个区块都可查询, 前 failures 次查询区块失败
+type fakeSource struct {
+	mu       sync.Mutex
+	height   uint64
+	failures int
+}
+
+func (s *fakeSource) setHeight(height uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.height = height
+}
+
+func (s *fakeSource) QueryInfo() (*blockchain.BlockchainInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &blockchain.BlockchainInfo{Height: s.height}, nil
+}
+
+func (s *fakeSource) QueryBlock(number uint64) (*blockchain.Block, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return nil, errors.New("peer unavailable")
+	}
+	if number >= s.height {
+		return nil, errors.Errorf("block %d not found", number)
+	}
+	return &blockchain.Block{Number: number}, nil
+}
+
+// delivery 接收方收到的一次投递
+type delivery struct {
+	header http.Header
+	body   []byte
+	event  blockchain.Event
+	at     time.Time
+}
+
+// receiver 记录所有投递, status 决定第 n 次投递的响应码
+type receiver struct {
+	mu         sync.Mutex
+	deliveries []delivery
+	status     func(n int) int
+}
+
+func newReceiver(t *testing.T, status func(n int) int) (*receiver, *httptest.Server) {
+	r := &receiver{status: status}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			t.Errorf("failed to read body: %v", err)
+		}
+		var e blockchain.Event
+		if err := json.Unmarshal(body, &e); err != nil {
+			t.Errorf("failed to decode event: %v", err)
+		}
+
+		r.mu.Lock()
+		r.deliveries = append(r.deliveries, delivery{header: req.Header, body: body, event: e, at: time.Now()})
+		n := len(r.deliveries)
+		r.mu.Unlock()
+
+		w.WriteHeader(r.status(n))
+	}))
+	return r, server
+}
+
+func (r *receiver) received() []delivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]delivery(nil), r.deliveries...)
+}
+
+func alwaysStatus(status int) func(int) int {
+	return func(int) int { return status }
+}
+
+func newTestDispatcher(t *testing.T, source BlockSource, path string, config Config) *Dispatcher {
+	d, err := NewDispatcher(source, path, config)
+	if err != nil {
+		t.Fatalf("NewDispatcher: %v", err)
+	}
+	return d
+}
+
+func tempDB(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "webhook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "webhook.db"), func() { os.RemoveAll(dir) }
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// subscribeBlocks 订阅区块事件后产生一个新区块
+func subscribeBlocks(t *testing.T, d *Dispatcher, source *fakeSource, url, secret string) *Subscription {
+	sub, err := d.Subscribe(&Subscription{URL: url, EventType: blockchain.EventBlock, Secret: secret})
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	source.setHeight(1)
+	d.advanceHeight(1)
+	return sub
+}
+
+func TestDeliverSignsBody(t *testing.T) {
+	r, server := newReceiver(t, alwaysStatus(http.StatusOK))
+	defer server.Close()
+	path, cleanup := tempDB(t)
+	defer cleanup()
+
+	source := &fakeSource{}
+	d := newTestDispatcher(t, source, path, Config{})
+	defer d.Close()
+
+	sub := subscribeBlocks(t, d, source, server.URL, "s3cret")
+	waitFor(t, "delivery", func() bool { return len(r.received()) == 1 })
+
+	got := r.received()[0]
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(got.body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if signature := got.header.Get(HeaderSignature); signature != want {
+		t.Errorf("signature = %q, want %q", signature, want)
+	}
+	if id := got.header.Get(HeaderSubscription); id != sub.ID {
+		t.Errorf("subscription header = %q, want %q", id, sub.ID)
+	}
+	if event := got.header.Get(HeaderEvent); event != blockchain.EventBlock {
+		t.Errorf("event header = %q, want %q", event, blockchain.EventBlock)
+	}
+	if got.event.BlockNumber != 0 {
+		t.Errorf("block number = %d, want 0", got.event.BlockNumber)
+	}
+}
+
+func TestDeliverWithoutSecretIsUnsigned(t *testing.T) {
+	r, server := newReceiver(t, alwaysStatus(http.StatusOK))
+	defer server.Close()
+	path, cleanup := tempDB(t)
+	defer cleanup()
+
+	source := &fakeSource{}
+	d := newTestDispatcher(t, source, path, Config{})
+	defer d.Close()
+
+	subscribeBlocks(t, d, source, server.URL, "")
+	waitFor(t, "delivery", func() bool { return len(r.received()) == 1 })
+
+	if signature := r.received()[0].header.Get(HeaderSignature); signature != "" {
+		t.Errorf("signature = %q, want none", signature)
+	}
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	// The first two attempts fail, the third one succeeds
+	r, server := newReceiver(t, func(n int) int {
+		if n <= 2 {
+			return http.StatusInternalServerError
+		}
+		return http.StatusOK
+	})
+	defer server.Close()
+	path, cleanup := tempDB(t)
+	defer cleanup()
+
+	source := &fakeSource{}
+	backoff := 20 * time.Millisecond
+	d := newTestDispatcher(t, source, path, Config{MaxAttempts: 5, InitialBackoff: backoff, MaxBackoff: time.Second})
+	defer d.Close()
+
+	sub := subscribeBlocks(t, d, source, server.URL, "")
+	waitFor(t, "checkpoint", func() bool { return checkpoint(t, d, sub.ID) == 1 })
+
+	got := r.received()
+	if len(got) != 3 {
+		t.Fatalf("attempts = %d, want 3", len(got))
+	}
+	if gap := got[1].at.Sub(got[0].at); gap < backoff {
+		t.Errorf("first retry after %v, want at least %v", gap, backoff)
+	}
+	if gap := got[2].at.Sub(got[1].at); gap < 2*backoff {
+		t.Errorf("second retry after %v, want at least %v", gap, 2*backoff)
+	}
+
+	letters, err := d.DeadLetters(sub.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 0 {
+		t.Errorf("dead letters = %d, want 0", len(letters))
+	}
+}
+
+func TestRetryAfterQueryFailure(t *testing.T) {
+	r, server := newReceiver(t, alwaysStatus(http.StatusOK))
+	defer server.Close()
+	path, cleanup := tempDB(t)
+	defer cleanup()
+
+	// No further block is committed, the worker must retry on its own
+	source := &fakeSource{failures: 2}
+	d := newTestDispatcher(t, source, path, Config{InitialBackoff: 5 * time.Millisecond, MaxBackoff: 10 * time.Millisecond})
+	defer d.Close()
+
+	sub := subscribeBlocks(t, d, source, server.URL, "")
+	waitFor(t, "checkpoint", func() bool { return checkpoint(t, d, sub.ID) == 1 })
+	if len(r.received()) != 1 {
+		t.Errorf("deliveries = %d, want 1", len(r.received()))
+	}
+}
+
+func TestSubscribeReturnsSnapshot(t *testing.T) {
+	_, server := newReceiver(t, alwaysStatus(http.StatusOK))
+	defer server.Close()
+	path, cleanup := tempDB(t)
+	defer cleanup()
+
+	source := &fakeSource{}
+	d := newTestDispatcher(t, source, path, Config{})
+	defer d.Close()
+
+	sub := subscribeBlocks(t, d, source, server.URL, "")
+	waitFor(t, "checkpoint", func() bool { return checkpoint(t, d, sub.ID) == 1 })
+
+	// The worker advances its own copy, not the subscription handed back to the caller
+	if sub.Checkpoint != 0 {
+		t.Errorf("returned checkpoint = %d, want 0", sub.Checkpoint)
+	}
+}
+
+func TestDeadLetterAfterMaxAttempts(t *testing.T) {
+	r, server := newReceiver(t, alwaysStatus(http.StatusServiceUnavailable))
+	defer server.Close()
+	path, cleanup := tempDB(t)
+	defer cleanup()
+
+	source := &fakeSource{}
+	d := newTestDispatcher(t, source, path, Config{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond})
+	defer d.Close()
+
+	sub := subscribeBlocks(t, d, source, server.URL, "")
+	waitFor(t, "dead letter", func() bool {
+		letters, err := d.DeadLetters(sub.ID)
+		return err == nil && len(letters) == 1
+	})
+
+	letters, err := d.DeadLetters(sub.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	letter := letters[0]
+	if letter.Attempts != 3 {
+		t.Errorf("attempts = %d, want 3", letter.Attempts)
+	}
+	if letter.Event == nil || letter.Event.BlockNumber != 0 {
+		t.Errorf("dead letter event = %+v, want block 0", letter.Event)
+	}
+	if len(r.received()) != 3 {
+		t.Errorf("deliveries = %d, want 3", len(r.received()))
+	}
+
+	// The failed event doesn't hold back the following blocks
+	waitFor(t, "checkpoint", func() bool { return checkpoint(t, d, sub.ID) == 1 })
+}
+
+func TestCheckpointSurvivesRestart(t *testing.T) {
+	r, server := newReceiver(t, alwaysStatus(http.StatusOK))
+	defer server.Close()
+	path, cleanup := tempDB(t)
+	defer cleanup()
+
+	source := &fakeSource{}
+	d := newTestDispatcher(t, source, path, Config{})
+	sub := subscribeBlocks(t, d, source, server.URL, "")
+	waitFor(t, "checkpoint", func() bool { return checkpoint(t, d, sub.ID) == 1 })
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// Blocks committed while the dispatcher is down are delivered after the restart
+	source.setHeight(3)
+	d = newTestDispatcher(t, source, path, Config{})
+	defer d.Close()
+	waitFor(t, "checkpoint", func() bool { return checkpoint(t, d, sub.ID) == 3 })
+
+	var blocks []uint64
+	for _, got := range r.received() {
+		blocks = append(blocks, got.event.BlockNumber)
+	}
+	if len(blocks) != 3 || blocks[0] != 0 || blocks[1] != 1 || blocks[2] != 2 {
+		t.Errorf("delivered blocks = %v, want [0 1 2]", blocks)
+	}
+}
+
+// checkpoint 返回存储中订阅的检查点
+func checkpoint(t *testing.T, d *Dispatcher, id string) uint64 {
+	subs, err := d.Subscriptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sub := range subs {
+		if sub.ID == id {
+			return sub.Checkpoint
+		}
+	}
+	t.Fatalf("subscription %s not found", id)
+	return 0
+}
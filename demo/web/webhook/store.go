@@ -0,0 +1,178 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"bcfish.cn/demo/web/blockchain"
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+)
+
+var (
+	bucketSubscriptions = []byte("subscriptions")
+	bucketDeadLetters   = []byte("dead_letters")
+)
+
+// Subscription webhook订阅
+type Subscription struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	// EventType 事件类型, blockchain.EventChaincode 或 blockchain.EventBlock
+	EventType   string `json:"event_type"`
+	ChaincodeID string `json:"chaincode_id,omitempty"`
+	// EventFilter 链码事件名的正则表达式, 为空时匹配所有事件
+	EventFilter string `json:"event_filter,omitempty"`
+	Secret      string `json:"secret,omitempty"`
+	// Checkpoint 下一个待投递的区块号
+	Checkpoint uint64    `json:"checkpoint"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DeadLetter 重试耗尽后仍投递失败的事件
+type DeadLetter struct {
+	SubscriptionID string            `json:"subscription_id"`
+	Event          *blockchain.Event `json:"event"`
+	Attempts       int               `json:"attempts"`
+	Error          string            `json:"error"`
+	FailedAt       time.Time         `json:"failed_at"`
+}
+
+// store 基于 BoltDB 的订阅与死信存储
+type store struct {
+	db *bolt.DB
+}
+
+func openStore(path string) (*store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open webhook database")
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketSubscriptions, bucketDeadLetters} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "failed to create webhook buckets")
+	}
+	return &store{db: db}, nil
+}
+
+func (s *store) close() error {
+	return s.db.Close()
+}
+
+func (s *store) saveSubscription(sub *Subscription) error {
+	value, err := json.Marshal(sub)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketSubscriptions).Put([]byte(sub.ID), value)
+	})
+}
+
+// saveCheckpoint 只更新已存在订阅的检查点, 订阅已删除时忽略
+func (s *store) saveCheckpoint(id string, checkpoint uint64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketSubscriptions)
+		value := bucket.Get([]byte(id))
+		if value == nil {
+			return nil
+		}
+		sub := &Subscription{}
+		if err := json.Unmarshal(value, sub); err != nil {
+			return err
+		}
+		sub.Checkpoint = checkpoint
+		value, err := json.Marshal(sub)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(id), value)
+	})
+}
+
+func (s *store) deleteSubscription(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketSubscriptions)
+		if bucket.Get([]byte(id)) == nil {
+			return errors.Errorf("subscription %s not found", id)
+		}
+		if err := bucket.Delete([]byte(id)); err != nil {
+			return err
+		}
+
+		// 同时删除该订阅的死信
+		prefix := deadLetterPrefix(id)
+		deadLetters := tx.Bucket(bucketDeadLetters)
+		cursor := deadLetters.Cursor()
+		for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Seek(prefix) {
+			if err := deadLetters.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *store) subscriptions() ([]*Subscription, error) {
+	var result []*Subscription
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketSubscriptions).ForEach(func(k, v []byte) error {
+			sub := &Subscription{}
+			if err := json.Unmarshal(v, sub); err != nil {
+				return err
+			}
+			result = append(result, sub)
+			return nil
+		})
+	})
+	return result, err
+}
+
+func (s *store) addDeadLetter(letter *DeadLetter) error {
+	value, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketDeadLetters)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+		return bucket.Put(append(deadLetterPrefix(letter.SubscriptionID), key...), value)
+	})
+}
+
+func (s *store) deadLetters(id string) ([]*DeadLetter, error) {
+	var result []*DeadLetter
+	err := s.db.View(func(tx *bolt.Tx) error {
+		prefix := deadLetterPrefix(id)
+		cursor := tx.Bucket(bucketDeadLetters).Cursor()
+		for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+			letter := &DeadLetter{}
+			if err := json.Unmarshal(v, letter); err != nil {
+				return err
+			}
+			result = append(result, letter)
+		}
+		return nil
+	})
+	return result, err
+}
+
+func deadLetterPrefix(id string) []byte {
+	return append([]byte(id), 0)
+}